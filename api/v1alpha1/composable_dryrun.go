@@ -0,0 +1,224 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	sdk "github.com/composable-operator/composable/sdk"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// dryRunFieldManager - the field manager of the dry-run updates, the controller applies the templates with the same name
+	dryRunFieldManager = "composable"
+
+	// the placeholders of the values of unresolved object references, by the type of the value
+	placeholderString   = "placeholder"
+	placeholderBytes    = "cGxhY2Vob2xkZXI=" // "placeholder" in base64
+	placeholderDateTime = "1970-01-01T00:00:00Z"
+)
+
+// pathSeparators matches the separators of the elements of field paths, e.g. "spec.ports[0]" or "data[key]"
+var pathSeparators = regexp.MustCompile(`[.\[\]]+`)
+
+// dryRun validates a template against the API server, by creating or updating its underlying object in dry-run mode.
+// The object references of the template are resolved if all their input objects exist, otherwise the template with
+// placeholders is used. The errors of the fields that are set by placeholders are ignored, because the placeholder
+// values are not the values that the template will be resolved to.
+func (r *Composable) dryRun(ctx context.Context, template, substituted map[string]interface{}, refs map[string]interface{},
+	placeholders []*field.Path, fieldpath *field.Path,
+) field.ErrorList {
+	u := &unstructured.Unstructured{}
+	_, err := webhookClients.resolver.ResolveObjectWithOptions(ctx, template, &u.Object, sdk.ResolveOptions{Refs: refs, RefsPath: field.NewPath("spec", "refs")})
+	if err != nil {
+		composablelog.Info("dry-run with placeholders", "name", r.Name, "reason", err.Error())
+		u.Object = substituted
+	} else {
+		placeholders = nil
+	}
+
+	// the resolved object is not logged, it may hold values of Secrets
+	composablelog.Info("dry-run", "name", r.Name, "kind", u.GroupVersionKind(), "object", u.GetName())
+	err = webhookClients.client.Create(ctx, u.DeepCopy(), client.DryRunAll)
+	if apierrors.IsAlreadyExists(err) {
+		// the underlying object is updated as the controller does it, if the controller would adopt it
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(u.GroupVersionKind())
+		if err := webhookClients.client.Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+			composablelog.Info("dry-run without adoption check", "name", r.Name, "err", err.Error())
+		} else if adopted, msg := r.adoption(existing); len(msg) > 0 {
+			return field.ErrorList{field.Forbidden(fieldpath.Child("metadata", "name"), msg)}
+		} else {
+			opts := []client.PatchOption{client.DryRunAll, client.FieldOwner(dryRunFieldManager)}
+			if r.Spec.ForceConflicts || (adopted && r.Spec.AdoptionPolicy == AdoptionPolicyAlways) {
+				opts = append(opts, client.ForceOwnership)
+			}
+			err = webhookClients.client.Patch(ctx, u, client.Apply, opts...)
+		}
+	}
+	if err == nil {
+		composablelog.Info("dry-run passed", "name", r.Name)
+		return nil
+	}
+	composablelog.Info("dry-run failed", "name", r.Name, "err", err.Error())
+	return r.dryRunErrors(err, placeholders, fieldpath)
+}
+
+// adoption mirrors the adoption policy of the controller for an existing underlying object. It returns true if the
+// controller would adopt the object, or the reason why it would refuse to adopt it.
+func (r *Composable) adoption(existing *unstructured.Unstructured) (bool, string) {
+	owner := metav1.GetControllerOf(existing)
+	if owner != nil && owner.UID == r.UID {
+		return false, ""
+	}
+	policy := r.Spec.AdoptionPolicy
+	if len(policy) == 0 {
+		policy = AdoptionPolicyIfUnowned
+	}
+	objName := fmt.Sprintf("%s %s/%s", existing.GetKind(), existing.GetNamespace(), existing.GetName())
+	switch {
+	case policy == AdoptionPolicyNever:
+		return false, fmt.Sprintf("%s already exists, and the adoption policy is %s", objName, policy)
+	case owner != nil && policy == AdoptionPolicyIfUnowned:
+		return false, fmt.Sprintf("%s is controlled by %s %s, and the adoption policy is %s", objName, owner.Kind, owner.Name, policy)
+	}
+	return true, ""
+}
+
+// dryRunErrors converts the error of a dry-run to field errors of the template, except the errors of the fields that are
+// set by the placeholders at the given template paths. Errors that do not result from an invalid template, e.g. a missing
+// permission, are ignored.
+func (r *Composable) dryRunErrors(err error, placeholders []*field.Path, fieldpath *field.Path) field.ErrorList {
+	if !apierrors.IsInvalid(err) && !apierrors.IsBadRequest(err) {
+		return nil
+	}
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil || len(status.Status().Details.Causes) == 0 {
+		return field.ErrorList{field.Invalid(fieldpath, r.Name, err.Error())}
+	}
+	var allErrs field.ErrorList
+	for _, cause := range status.Status().Details.Causes {
+		if len(cause.Field) == 0 {
+			allErrs = append(allErrs, field.Invalid(fieldpath, r.Name, cause.Message))
+			continue
+		}
+		causePath := toTemplatePath(fieldpath, cause.Field)
+		if !setByPlaceholder(causePath, placeholders) {
+			allErrs = append(allErrs, field.Invalid(causePath, r.Name, cause.Message))
+		}
+	}
+	return allErrs
+}
+
+// toTemplatePath returns the path of a field of the underlying object in the template, e.g. spec.template.spec.replicas
+func toTemplatePath(fieldpath *field.Path, objectField string) *field.Path {
+	fldPath := fieldpath
+	for _, elem := range pathSeparators.Split(objectField, -1) {
+		if len(elem) == 0 {
+			continue
+		}
+		if i, err := strconv.Atoi(elem); err == nil {
+			fldPath = fldPath.Index(i)
+		} else {
+			fldPath = fldPath.Child(elem)
+		}
+	}
+	return fldPath
+}
+
+// setByPlaceholder returns true if the field is set by a placeholder, or is an element of a field set by a placeholder
+func setByPlaceholder(fldPath *field.Path, placeholders []*field.Path) bool {
+	f := fldPath.String()
+	for _, placeholder := range placeholders {
+		if p := placeholder.String(); f == p || strings.HasPrefix(f, p+".") || strings.HasPrefix(f, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// substitute replaces the object references and the strings with named references placeholders by placeholders of
+// the types defined by the schema of the value, and appends the paths of the placeholders to placeholders.
+// The schema and its kind schema are nil if they are unknown, the value is changed in place.
+func (k *kindSchema) substitute(value interface{}, s *spec.Schema, fldPath *field.Path, placeholders *[]*field.Path) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v[getValueFrom]; ok {
+			*placeholders = append(*placeholders, fldPath)
+			return k.placeholder(s)
+		}
+		for key, elem := range v {
+			v[key] = k.substitute(elem, k.property(s, key), fldPath.Child(key), placeholders)
+		}
+		return v
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = k.substitute(elem, k.item(s), fldPath.Index(i), placeholders)
+		}
+		return v
+	case string:
+		if strings.Contains(v, "${") {
+			*placeholders = append(*placeholders, fldPath)
+			return k.placeholder(s)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// placeholder returns a value of the type defined by the schema, a string if the schema is unknown
+func (k *kindSchema) placeholder(s *spec.Schema) interface{} {
+	s = k.resolve(s)
+	switch {
+	case s == nil:
+		return placeholderString
+	case len(s.Enum) > 0:
+		return s.Enum[0]
+	case s.Extensions[intOrStringExtension] == true, s.Type.Contains("integer"):
+		if s.Minimum != nil && *s.Minimum > 1 {
+			return int64(*s.Minimum)
+		}
+		return int64(1)
+	case s.Type.Contains("number"):
+		if s.Minimum != nil && *s.Minimum > 1 {
+			return *s.Minimum
+		}
+		return float64(1)
+	case s.Type.Contains("boolean"):
+		return false
+	case s.Type.Contains("object"):
+		return map[string]interface{}{}
+	case s.Type.Contains("array"):
+		return []interface{}{}
+	case s.Format == "byte":
+		return placeholderBytes
+	case s.Format == "date-time":
+		return placeholderDateTime
+	default:
+		return placeholderString
+	}
+}
@@ -23,11 +23,158 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// Composable condition types
+const (
+	// ConditionReady - the underlying object is created and up-to-date with the resolved template
+	ConditionReady = "Ready"
+
+	// ConditionResolved - all object references of the template are resolved
+	ConditionResolved = "Resolved"
+
+	// ConditionApplied - the resolved template is applied to the underlying object
+	ConditionApplied = "Applied"
+
+	// ConditionDegraded - the last reconciliation of the Composable failed
+	ConditionDegraded = "Degraded"
+
+	// ConditionSuspended - the reconciliation of the Composable is suspended
+	ConditionSuspended = "Suspended"
+)
+
+// Composable condition reasons
+const (
+	// ReasonResolved - all object references are resolved
+	ReasonResolved = "Resolved"
+
+	// ReasonApplied - the underlying object is created or updated
+	ReasonApplied = "Applied"
+
+	// ReasonPending - the Composable object is not reconciled yet
+	ReasonPending = "Pending"
+
+	// ReasonInvalidTemplate - the template cannot be parsed or misses required fields
+	ReasonInvalidTemplate = "InvalidTemplate"
+
+	// ReasonIllFormedRef - an object reference is ill-formed
+	ReasonIllFormedRef = "IllFormedRef"
+
+	// ReasonKindNotFound - the kind of a referenced object cannot be discovered
+	ReasonKindNotFound = "KindNotFound"
+
+	// ReasonObjectNotFound - a referenced object does not exist
+	ReasonObjectNotFound = "ObjectNotFound"
+
+	// ReasonValueNotFound - a referenced value does not exist in the referenced object
+	ReasonValueNotFound = "ValueNotFound"
+
+	// ReasonInputNotReady - a referenced object does not satisfy the waitFor element of its reference yet
+	ReasonInputNotReady = "InputNotReady"
+
+	// ReasonResolveError - an object reference cannot be resolved for another reason
+	ReasonResolveError = "ResolveError"
+
+	// ReasonApplyError - the underlying object cannot be created or updated
+	ReasonApplyError = "ApplyError"
+
+	// ReasonApplyConflict - fields of the underlying object are managed by other field managers
+	ReasonApplyConflict = "ApplyConflict"
+
+	// ReasonSuspended - the reconciliation is suspended by the spec or the annotation
+	ReasonSuspended = "Suspended"
+
+	// ReasonResumed - the reconciliation is not suspended
+	ReasonResumed = "Resumed"
+
+	// ReasonAdoptionRefused - the underlying object exists, and the adoption policy does not allow to take it over
+	ReasonAdoptionRefused = "AdoptionRefused"
+)
+
+const (
+	// DeletionPolicyDelete - the underlying objects are deleted with the Composable
+	DeletionPolicyDelete = "Delete"
+
+	// DeletionPolicyOrphan - the underlying objects are kept without the owner reference to the Composable
+	DeletionPolicyOrphan = "Orphan"
+
+	// DeletionPolicyRetain - the deletion of the Composable waits for the AllowDeletionAnnotation
+	DeletionPolicyRetain = "Retain"
+
+	// AdoptionPolicyNever - existing underlying objects are not taken over
+	AdoptionPolicyNever = "Never"
+
+	// AdoptionPolicyIfUnowned - existing underlying objects are taken over if they have no controller
+	AdoptionPolicyIfUnowned = "IfUnowned"
+
+	// AdoptionPolicyAlways - existing underlying objects are taken over, even from another controller
+	AdoptionPolicyAlways = "Always"
+
+	// DriftPolicyCorrect - drifted underlying objects are reported and updated with the template
+	DriftPolicyCorrect = "Correct"
+
+	// DriftPolicyReport - drifted underlying objects are reported, but not updated
+	DriftPolicyReport = "Report"
+
+	// DriftPolicyIgnore - drifted underlying objects are neither reported nor updated
+	DriftPolicyIgnore = "Ignore"
+
+	// SuspendAnnotation - suspends the reconciliation of a Composable, if it is "true"
+	SuspendAnnotation = "ibmcloud.ibm.com/suspend"
+
+	// AllowDeletionAnnotation - allows the deletion of a Composable with the Retain deletion policy, if it is "true"
+	AllowDeletionAnnotation = "ibmcloud.ibm.com/allow-deletion"
+)
+
 // ComposableSpec defines the desired state of Composable
 type ComposableSpec struct {
 	// Template defines the underlying object
 	//+kubebuilder:validation:XPreserveUnknownFields
-	Template *runtime.RawExtension `json:"template"`
+	// +optional
+	Template *runtime.RawExtension `json:"template,omitempty"`
+
+	// Templates defines several underlying objects, they are created in order after the Template object.
+	// All templates are resolved together, so they share the values of the input objects.
+	// +optional
+	Templates []runtime.RawExtension `json:"templates,omitempty"`
+
+	// Refs declares named object references, that have the format of getValueFrom elements. Every ${name} placeholder
+	// in the strings of the templates is replaced by the value of the reference with that name, and "$${" escapes a
+	// placeholder. A string that consists of a single placeholder is replaced by the value itself, keeping its type.
+	// Once Refs is not empty, every "${" in every template is a placeholder, including strings that are not meant as
+	// placeholders, e.g. shell scripts or other templates. Such strings should be escaped with "$${" before refs are
+	// declared, otherwise the Composable is rejected, since they do not refer to declared references.
+	// +optional
+	Refs map[string]runtime.RawExtension `json:"refs,omitempty"`
+
+	// ForceConflicts - forces the Composable to take the ownership of fields of the underlying object that are
+	// managed by other field managers and have different values in the template
+	// +optional
+	ForceConflicts bool `json:"forceConflicts,omitempty"`
+
+	// DeletionPolicy - defines what happens to the underlying objects when the Composable is deleted.
+	// Delete (the default) deletes them, Orphan keeps them without the owner reference to the Composable, and Retain
+	// keeps the Composable and its underlying objects until the ibmcloud.ibm.com/allow-deletion annotation is "true".
+	// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy - defines whether the Composable takes over an underlying object that exists, but is not controlled
+	// by the Composable. Never refuses to take it over, IfUnowned (the default) adopts it only if it has no controller,
+	// and Always replaces the controller reference of another owner.
+	// +kubebuilder:validation:Enum=Never;IfUnowned;Always
+	// +optional
+	AdoptionPolicy string `json:"adoptionPolicy,omitempty"`
+
+	// DriftPolicy - defines what happens when fields of an underlying object that are defined by the template are
+	// changed by someone else. Correct (the default) applies the template again, Report only reports the drifted
+	// fields, and Ignore neither reports nor corrects them. The template is applied whenever it changes.
+	// +kubebuilder:validation:Enum=Correct;Report;Ignore
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// Suspend - suspends the reconciliation of the Composable, its templates are neither resolved nor applied, so the
+	// underlying objects can be changed by hand. The ibmcloud.ibm.com/suspend annotation set to "true" suspends it as well.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // ComposableStatus defines the observed state of Composable
@@ -40,11 +187,93 @@ type ComposableStatus struct {
 	// Message - provides human readable explanation of the Composable status
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// Objects - reports the state of every underlying object, in the order of the templates
+	// +optional
+	Objects []UnderlyingObjectStatus `json:"objects,omitempty"`
+
+	// Inputs - lists the objects that the template values were read from during the last reconciliation
+	// +optional
+	Inputs []InputObjectReference `json:"inputs,omitempty"`
+
+	// ObservedGeneration - the Composable generation that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions - the latest observations of the Composable state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// UnderlyingObjectStatus reports the state of an underlying object
+type UnderlyingObjectStatus struct {
+	// APIVersion of the underlying object
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the underlying object
+	Kind string `json:"kind"`
+
+	// Name of the underlying object
+	Name string `json:"name"`
+
+	// State shows the underlying object state
+	// +kubebuilder:validation:Enum=Failed;Pending;Online
+	State string `json:"state"`
+
+	// Message - provides human readable explanation of the underlying object state
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// TemplateHash - the hash of the template that the underlying object was last applied with
+	// +optional
+	TemplateHash string `json:"templateHash,omitempty"`
+
+	// DriftedFields - the template fields that were last changed by someone else
+	// +optional
+	DriftedFields []string `json:"driftedFields,omitempty"`
+
+	// LastDriftTime - the time when the drift of the DriftedFields was detected
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+}
+
+// InputObjectReference identifies an input object, or a set of input objects selected by labels or fields
+type InputObjectReference struct {
+	// APIVersion of the input object
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the input object
+	Kind string `json:"kind"`
+
+	// Namespace of the input object, empty for cluster scoped objects
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the input object, empty if the input objects are selected by labels
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Labels that select the input objects
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// LabelSelector that selects the input objects, in its string form, e.g. "app in (db,web)"
+	// +optional
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	// FieldSelector that selects the input objects, in its string form, e.g. "status.phase=Running"
+	// +optional
+	FieldSelector string `json:"fieldSelector,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=composables,scope=Namespaced,shortName=comp
 // +kubebuilder:printcolumn:name="State",type=string,JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 // +kubebuilder:printcolumn:name="Resource Name",type=string,JSONPath=".spec.template.metadata.name"
 // +kubebuilder:printcolumn:name="Resource Kind",type=string,JSONPath=".spec.template.kind"
 // +kubebuilder:printcolumn:name="Resource apiVersion",type=string,JSONPath=".spec.template.apiVersion"
@@ -59,6 +288,18 @@ type Composable struct {
 	Status ComposableStatus `json:"status,omitempty"`
 }
 
+// AllTemplates returns the Template followed by the Templates of the spec
+func (in *ComposableSpec) AllTemplates() []*runtime.RawExtension {
+	var templates []*runtime.RawExtension
+	if in.Template != nil {
+		templates = append(templates, in.Template)
+	}
+	for i := range in.Templates {
+		templates = append(templates, &in.Templates[i])
+	}
+	return templates
+}
+
 // +kubebuilder:object:root=true
 
 // ComposableList contains a list of Composable
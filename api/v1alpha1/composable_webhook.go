@@ -17,19 +17,17 @@ package v1alpha1
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	sdk "github.com/composable-operator/composable/sdk"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -48,6 +46,11 @@ const (
 
 // SetupWebhookWithManager sets up the webhooks with the manager
 func (r *Composable) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	clients, err := newAPIServerClients(mgr)
+	if err != nil {
+		return err
+	}
+	webhookClients = clients
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -75,13 +78,13 @@ var _ webhook.Validator = &Composable{}
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *Composable) ValidateCreate() error {
 	composablelog.Info("validate create", "name", r.Name)
-	return r.validateComposable(OperationCreate)
+	return r.validateComposable()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *Composable) ValidateUpdate(old runtime.Object) error {
 	composablelog.Info("validate update", "name", r.Name)
-	return r.validateComposable(OperationUpdate)
+	return r.validateComposable()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -92,24 +95,50 @@ func (r *Composable) ValidateDelete() error {
 	return nil
 }
 
-// validateComposable validates the spec.template of the request
-func (r *Composable) validateComposable(operation string) error {
+// validateComposable validates the spec.template and spec.templates of the request
+func (r *Composable) validateComposable() error {
 	composablelog.Info("validateComposable", "name", r.Name)
-	allErrs := r.validateAPIVersionKind(r.Spec.Template, field.NewPath("spec").Child("template"))
-	m, err := r.validate(r.Spec.Template, field.NewPath("spec").Child("template"))
-	if err != nil {
-		allErrs = append(allErrs, err...)
+	var allErrs field.ErrorList
+	var instances []map[string]interface{}
+	var templatePaths []*field.Path
+	specPath := field.NewPath("spec")
+	refs, refsErrs := r.validateRefs(specPath.Child("refs"))
+	allErrs = append(allErrs, refsErrs...)
+	validateTemplate := func(template *runtime.RawExtension, fieldpath *field.Path) {
+		allErrs = append(allErrs, r.validateAPIVersionKind(template, fieldpath)...)
+		allErrs = append(allErrs, r.validatePlaceholders(template, refs, fieldpath)...)
+		m, err := r.validate(template, fieldpath)
+		if err != nil {
+			allErrs = append(allErrs, err...)
+		}
+		allErrs = append(allErrs, r.validateNamespace(m, fieldpath)...)
+		instances = append(instances, m)
+		templatePaths = append(templatePaths, fieldpath)
+	}
+
+	if r.Spec.Template == nil && len(r.Spec.Templates) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("template"), "Either template or templates must be set"))
+	}
+	if r.Spec.Template != nil {
+		validateTemplate(r.Spec.Template, specPath.Child("template"))
+	}
+	for i := range r.Spec.Templates {
+		validateTemplate(&r.Spec.Templates[i], specPath.Child("templates").Index(i))
 	}
 	if len(allErrs) > 0 {
 		return apierrors.NewInvalid(schema.GroupKind{Group: "ibmcloud.ibm.com", Kind: "Composable"}, r.Name, allErrs)
 	}
 
-	composablelog.Info("validateComposable", "name", r.Name, "dry-run-instance", m)
-	//disable for now, need more work on inserting valid values per schema
-	/*	if err := r.dryRun(m, operation); err != nil {
-		allErrs = append(allErrs, err)
+	composablelog.Info("validateComposable", "name", r.Name, "instances", instances)
+	// the errors of API server requests, e.g. a timeout, are ignored, as the errors of the dry-run
+	ctx, cancel := context.WithTimeout(context.Background(), apiServerValidationTimeout)
+	defer cancel()
+	for i, instance := range instances {
+		allErrs = append(allErrs, r.validateWithAPIServer(ctx, instance, refs, templatePaths[i])...)
+	}
+	if len(allErrs) > 0 {
 		return apierrors.NewInvalid(schema.GroupKind{Group: "ibmcloud.ibm.com", Kind: "Composable"}, r.Name, allErrs)
-	}*/
+	}
 	return nil
 }
 
@@ -130,6 +159,50 @@ func (r *Composable) validateAPIVersionKind(template *runtime.RawExtension, fiel
 	return allErrs
 }
 
+// validateNamespace validates that the namespace of the template, if it is defined, is the namespace of the Composable,
+// because the controller creates the underlying objects in it. Namespaces set by object references are not validated.
+func (r *Composable) validateNamespace(m map[string]interface{}, fieldpath *field.Path) field.ErrorList {
+	metadata, _ := m[sdk.Metadata].(map[string]interface{})
+	ns, ok := metadata[sdk.Namespace]
+	if !ok {
+		return nil
+	}
+	if _, isRef := ns.(map[string]interface{}); isRef {
+		return nil
+	}
+	if str, isString := ns.(string); isString && strings.Contains(str, "${") {
+		return nil
+	}
+	if ns != r.Namespace {
+		return field.ErrorList{field.Invalid(fieldpath.Child(sdk.Metadata, sdk.Namespace), ns,
+			fmt.Sprintf("the underlying objects are created in the namespace of the Composable %s", r.Namespace))}
+	}
+	return nil
+}
+
+// validateRefs parses and validates the named object references in spec.refs
+func (r *Composable) validateRefs(fieldpath *field.Path) (map[string]interface{}, field.ErrorList) {
+	var allErrs field.ErrorList
+	refs := make(map[string]interface{}, len(r.Spec.Refs))
+	for name, raw := range r.Spec.Refs {
+		var ref interface{}
+		if err := json.Unmarshal(raw.Raw, &ref); err != nil {
+			allErrs = append(allErrs, field.Invalid(fieldpath.Child(name), r.Name, err.Error()))
+			continue
+		}
+		refs[name] = ref
+	}
+	allErrs = append(allErrs, r.referenceErrors(sdk.ValidateRefs(refs, fieldpath))...)
+	return refs, allErrs
+}
+
+// validatePlaceholders validates that the placeholders in the template strings refer to declared references
+func (r *Composable) validatePlaceholders(template *runtime.RawExtension, refs map[string]interface{}, fieldpath *field.Path) field.ErrorList {
+	var f interface{}
+	json.Unmarshal(template.Raw, &f)
+	return r.referenceErrors(sdk.ValidatePlaceholders(f, refs, fieldpath))
+}
+
 // validate validates the template for the required fields of getValueFrom
 func (r *Composable) validate(template *runtime.RawExtension, fieldpath *field.Path) (map[string]interface{}, field.ErrorList) {
 	var f interface{}
@@ -152,11 +225,7 @@ func (r *Composable) findGetValueFrom(path *field.Path, m map[string]interface{}
 			}
 		case map[string]interface{}:
 			if vv[getValueFrom] != nil {
-				if err := validateGetValueFrom(vv[getValueFrom]); err != nil {
-					allErrs = append(allErrs, field.Invalid(mykey.Child(getValueFrom), r.Name, err.Error()))
-				}
-				// TODO: set the value to an appropriate type e.g. int, string, etc
-				m[k] = "abc"
+				allErrs = append(allErrs, r.referenceErrors(sdk.ValidateReference(vv[getValueFrom], mykey.Child(getValueFrom)))...)
 			} else { // recursive checking the sub-elements
 				if err := r.findGetValueFrom(mykey, vv); err != nil {
 					allErrs = append(allErrs, err...)
@@ -183,11 +252,7 @@ func (r *Composable) findGetValueFrom2(path *field.Path, m []interface{}) field.
 			}
 		case map[string]interface{}:
 			if vv[getValueFrom] != nil {
-				if err := validateGetValueFrom(vv[getValueFrom]); err != nil {
-					allErrs = append(allErrs, field.Invalid(mykey.Child(getValueFrom), r.Name, err.Error()))
-				}
-				// TODO: set a random value of appropriate type for dry-run
-				m[k] = "abc2"
+				allErrs = append(allErrs, r.referenceErrors(sdk.ValidateReference(vv[getValueFrom], mykey.Child(getValueFrom)))...)
 			} else {
 				if err := r.findGetValueFrom(mykey, vv); err != nil {
 					allErrs = append(allErrs, err...)
@@ -200,79 +265,24 @@ func (r *Composable) findGetValueFrom2(path *field.Path, m []interface{}) field.
 	return allErrs
 }
 
-// validateGetValueFrom validates the syntax of input getValueFrom fields
-func validateGetValueFrom(v interface{}) error {
-	var missingItems []string
-	getValueFrom := sdk.ComposableGetValueFrom{}
-	message := ""
-	var labelsExist bool = false
-
-	obj, err := json.Marshal(v)
-	if err != nil {
-		return fmt.Errorf("Invalid getValueFrom - %v", err.Error())
-	}
-	json.Unmarshal(obj, &getValueFrom)
-
-	if val, ok := v.(map[string]interface{}); ok {
-		if _, ok := val["labels"].(map[string]interface{}); ok {
-			labelsExist = ok
-		}
-	}
-
-	if len(getValueFrom.Name) == 0 && !labelsExist {
-		missingItems = append(missingItems, "name or labels")
-	} else if len(getValueFrom.Name) > 0 && labelsExist {
-		message += "Cannot specify both name and labels (only one of them can be specified)"
-	}
-
-	if len(getValueFrom.Kind) == 0 {
-		missingItems = append(missingItems, "kind")
-	}
-	if len(getValueFrom.Path) == 0 {
-		missingItems = append(missingItems, "path")
-	}
-	if len(missingItems) > 0 {
-		items := array2string(missingItems)
-		message += "Missing required field(s) - " + items
-	}
-
-	if len(message) > 0 {
-		composablelog.Info("validateGetValueFrom", "message", message)
-		return fmt.Errorf(message)
-	}
-	return nil
-}
-
-func array2string(a []string) string {
-	str := ""
-	for _, v := range a {
-		str = v + "," + str
-	}
-	str = strings.TrimRight(str, ",")
-	return str
-}
-
-// dryrun as a means of syntax validation of the template content
-func (r *Composable) dryRun(m map[string]interface{}, op string) *field.Error {
-	cl, err := client.New(config.GetConfigOrDie(), client.Options{})
-	if err != nil {
-		return field.Invalid(field.NewPath("spec").Child("template"), r.Name, "dry-run failed to get client")
+// referenceErrors converts the errors of an object reference validation to field errors, located at the invalid fields
+func (r *Composable) referenceErrors(err error) field.ErrorList {
+	if err == nil {
+		return nil
 	}
-
-	u := unstructured.Unstructured{Object: m}
-	composablelog.Info("dry-run", "obj", u.Object)
-	if op == OperationCreate {
-		if err = cl.Create(context.TODO(), &u, client.DryRunAll); err != nil {
-			composablelog.Info("create dry-run failed", "name", r.Name, "err", err.Error())
-			return field.Invalid(field.NewPath("spec").Child("template"), r.Name, err.Error())
-		}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
 	}
-	if op == OperationUpdate {
-		if err = cl.Update(context.TODO(), &u, client.DryRunAll); err != nil {
-			composablelog.Info("update dry-run failed", "name", r.Name, "err", err.Error())
-			return field.Invalid(field.NewPath("spec").Child("template"), r.Name, err.Error())
+	var allErrs field.ErrorList
+	for _, e := range errs {
+		var resolveErr *sdk.ResolveError
+		if errors.As(e, &resolveErr) && resolveErr.Field != nil {
+			composablelog.Info("referenceErrors", "field", resolveErr.Field.String(), "message", resolveErr.Message)
+			allErrs = append(allErrs, field.Invalid(resolveErr.Field, r.Name, resolveErr.Message))
+		} else {
+			allErrs = append(allErrs, field.InternalError(field.NewPath("spec"), e))
 		}
 	}
-	composablelog.Info("dry-run passed", "name", r.Name)
-	return nil
+	return allErrs
 }
@@ -16,12 +16,24 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
 	"testing"
 
+	sdk "github.com/composable-operator/composable/sdk"
 	"github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestAdmissionControl(t *testing.T) {
@@ -109,11 +121,624 @@ func TestAdmissionControl(t *testing.T) {
 	_, err := createdGood.validate(createdGood.Spec.Template, field.NewPath("spec").Child("template"))
 	g.Expect(len(err)).To(gomega.BeZero())
 
-	// ToBeInvestigated (it fails to initiate the client)
-	// g.Expect(createdGood.dryRun(m, OperationCreate)).To(gomega.BeNil())
-
 	// Test validating webhook with an invalid template
 	g.Expect(createdBad.validateAPIVersionKind(createdBad.Spec.Template, field.NewPath("spec").Child("template"))).NotTo(gomega.BeNil())
 	_, err = createdBad.validate(createdBad.Spec.Template, field.NewPath("spec").Child("template"))
 	g.Expect(len(err)).NotTo(gomega.BeZero())
+
+	// Test validating webhook points at the invalid fields of the object references
+	g.Expect(err).To(gomega.ContainElement(gomega.HaveField("Field", "spec.template.data.key.getValueFrom.path")))
+	g.Expect(err).To(gomega.ContainElement(gomega.HaveField("Field", "spec.template.data.key.getValueFrom")))
+	g.Expect(err).To(gomega.ContainElement(gomega.HaveField("Field", "spec.template.data.dockerconfig.getValueFrom.kind")))
+}
+
+func TestAdmissionControlTemplates(t *testing.T) {
+	embeddedGood := []byte(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {
+		   "name": "configmapgood"
+		 },
+		"data": {
+		 "key": "value"
+		 }
+		}`)
+
+	embeddedBad := []byte(`{
+		"apiVersion": "v1",
+		"metadata": {
+		   "name": "configmapbad"
+		 }
+		}`)
+
+	newComposable := func(spec ComposableSpec) *Composable {
+		return &Composable{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Composable",
+				APIVersion: GroupVersion.String(),
+			},
+			Spec: spec,
+		}
+	}
+
+	g := gomega.NewGomegaWithT(t)
+
+	// Test validating webhook with valid templates
+	good := newComposable(ComposableSpec{Templates: []runtime.RawExtension{{Raw: embeddedGood}, {Raw: embeddedGood}}})
+	g.Expect(good.validateComposable()).To(gomega.Succeed())
+
+	// Test validating webhook with both template and templates
+	both := newComposable(ComposableSpec{Template: &runtime.RawExtension{Raw: embeddedGood}, Templates: []runtime.RawExtension{{Raw: embeddedGood}}})
+	g.Expect(both.validateComposable()).To(gomega.Succeed())
+
+	// Test validating webhook without any template
+	none := newComposable(ComposableSpec{})
+	err := none.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template"))
+
+	// Test validating webhook rejects unknown transformers
+	embeddedUnknownTransformer := []byte(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {
+		   "name": "configmaptransformer"
+		 },
+		"data": {
+		 "key": {
+		  "getValueFrom": {
+		   "kind": "Secret",
+		   "name": "mysecret",
+		   "path": "{.data.key}",
+		   "format-transformers": ["Base64ToString", "ToUpper"]
+		   }
+		  }
+		 }
+		}`)
+	unknown := newComposable(ComposableSpec{Templates: []runtime.RawExtension{{Raw: embeddedUnknownTransformer}}})
+	err = unknown.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[0].data.key.getValueFrom.format-transformers[1]"))
+
+	// Test validating webhook checks the arguments of transformers
+	embeddedTransformerArgs := []byte(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {
+		   "name": "configmaptransformer"
+		 },
+		"data": {
+		 "key": {
+		  "getValueFrom": {
+		   "kind": "ConfigMap",
+		   "name": "myconfigmap",
+		   "path": "{.data.hosts}",
+		   "format-transformers": [{"name": "Split", "args": [";"]}, {"name": "Index"}]
+		   }
+		  }
+		 }
+		}`)
+	args := newComposable(ComposableSpec{Templates: []runtime.RawExtension{{Raw: embeddedTransformerArgs}}})
+	err = args.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[0].data.key.getValueFrom.format-transformers[1]"))
+	g.Expect(err.Error()).NotTo(gomega.ContainSubstring("format-transformers[0]"))
+
+	// Test validating webhook rejects incompatible transformers and malformed jsonpaths
+	embeddedIncompatible := []byte(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {
+		   "name": "configmaptransformer"
+		 },
+		"data": {
+		 "key": {
+		  "getValueFrom": {
+		   "kind": "ConfigMap",
+		   "name": "myconfigmap",
+		   "path": "{.data.port",
+		   "format-transformers": ["StringToInt", "StringToInt"]
+		   }
+		  }
+		 }
+		}`)
+	incompatible := newComposable(ComposableSpec{Templates: []runtime.RawExtension{{Raw: embeddedIncompatible}}})
+	err = incompatible.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[0].data.key.getValueFrom.format-transformers[1]"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[0].data.key.getValueFrom.path"))
+	g.Expect(err.Error()).NotTo(gomega.ContainSubstring("format-transformers[0]"))
+
+	// Test validating webhook reports the index of an invalid template
+	bad := newComposable(ComposableSpec{Templates: []runtime.RawExtension{{Raw: embeddedGood}, {Raw: embeddedBad}}})
+	err = bad.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[1].kind"))
+
+	// Test validating webhook rejects namespaces other than the namespace of the Composable
+	namespaced := func(ns string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "cm", "namespace": ` + ns + `}}`)}
+	}
+	other := newComposable(ComposableSpec{Templates: []runtime.RawExtension{namespaced(`"default"`), namespaced(`"other"`)}})
+	err = other.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[1].metadata.namespace"))
+	g.Expect(err.Error()).NotTo(gomega.ContainSubstring("spec.templates[0]"))
+	referred := newComposable(ComposableSpec{Templates: []runtime.RawExtension{
+		namespaced(`{"getValueFrom": {"kind": "ConfigMap", "name": "myconfigmap", "path": "{.data.namespace}"}}`),
+	}})
+	g.Expect(referred.validateComposable()).To(gomega.Succeed())
+}
+
+func TestAdmissionControlRefs(t *testing.T) {
+	embeddedURL := []byte(`{
+		"apiVersion": "v1",
+		"kind": "ConfigMap",
+		"metadata": {
+		   "name": "configmapurl"
+		 },
+		"data": {
+		 "url": "postgres://${user}@${host}:5432/db",
+		 "script": "echo $${HOME}"
+		 }
+		}`)
+
+	newComposable := func(refs map[string]runtime.RawExtension) *Composable {
+		return &Composable{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Composable",
+				APIVersion: GroupVersion.String(),
+			},
+			Spec: ComposableSpec{Refs: refs, Templates: []runtime.RawExtension{{Raw: embeddedURL}}},
+		}
+	}
+
+	g := gomega.NewGomegaWithT(t)
+
+	// Test validating webhook with declared references
+	good := newComposable(map[string]runtime.RawExtension{
+		"user": {Raw: []byte(`{"kind": "Secret", "name": "db", "path": "{.data.user}", "format-transformers": ["Base64ToString"]}`)},
+		"host": {Raw: []byte(`{"kind": "Service", "name": "db", "path": "{.spec.clusterIP}"}`)},
+	})
+	g.Expect(good.validateComposable()).To(gomega.Succeed())
+
+	// Test validating webhook rejects placeholders of undeclared references and ill-formed references
+	bad := newComposable(map[string]runtime.RawExtension{
+		"user": {Raw: []byte(`{"kind": "Secret", "name": "db"}`)},
+	})
+	err := bad.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.refs.user.path"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.templates[0].data.url"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("${host}"))
+	g.Expect(err.Error()).NotTo(gomega.ContainSubstring("spec.templates[0].data.script"))
+}
+
+func TestAdmissionControlExpressions(t *testing.T) {
+	newComposable := func(expr string) *Composable {
+		template := []byte(`{
+			"apiVersion": "v1",
+			"kind": "ConfigMap",
+			"metadata": {
+			   "name": "configmapexpression"
+			 },
+			"data": {
+			 "port": {
+			  "getValueFrom": {
+			   "kind": "Service",
+			   "name": "db",
+			   "expression": ` + strconv.Quote(expr) + `
+			   }
+			  }
+			 }
+			}`)
+		return &Composable{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Composable",
+				APIVersion: GroupVersion.String(),
+			},
+			Spec: ComposableSpec{Template: &runtime.RawExtension{Raw: template}},
+		}
+	}
+
+	g := gomega.NewGomegaWithT(t)
+
+	// Test validating webhook with a valid expression
+	good := newComposable(`object.spec.ports.filter(p, p.name.matches("^http")).map(p, p.port)[0]`)
+	g.Expect(good.validateComposable()).To(gomega.Succeed())
+
+	// Test validating webhook rejects expressions that cannot be compiled
+	bad := newComposable(`object.spec.ports[0].port +`)
+	err := bad.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.data.port.getValueFrom.expression"))
+
+	// Test validating webhook rejects expressions that use undeclared references
+	undeclared := newComposable(`refs.port`)
+	err = undeclared.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("reference \"port\" is not declared"))
+}
+
+func TestAdmissionControlSelectors(t *testing.T) {
+	newComposable := func(selectors string) *Composable {
+		template := []byte(`{
+			"apiVersion": "v1",
+			"kind": "ConfigMap",
+			"metadata": {
+			   "name": "configmapselectors"
+			 },
+			"data": {
+			 "hosts": {
+			  "getValueFrom": {
+			   "kind": "Service",
+			   "path": "{.spec.clusterIP}",
+			   "aggregate": "list",
+			   ` + selectors + `
+			   }
+			  }
+			 }
+			}`)
+		return &Composable{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Composable",
+				APIVersion: GroupVersion.String(),
+			},
+			Spec: ComposableSpec{Template: &runtime.RawExtension{Raw: template}},
+		}
+	}
+
+	g := gomega.NewGomegaWithT(t)
+
+	// Test validating webhook with a label selector and a field selector
+	good := newComposable(`"labelSelector": {"matchExpressions": [{"key": "app", "operator": "In", "values": ["db", "web"]}]},
+			   "fieldSelector": "metadata.namespace=default"`)
+	g.Expect(good.validateComposable()).To(gomega.Succeed())
+
+	// Test validating webhook rejects unknown label selector operators
+	badOperator := newComposable(`"labelSelector": {"matchExpressions": [{"key": "app", "operator": "Like", "values": ["db"]}]}`)
+	err := badOperator.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.data.hosts.getValueFrom.labelSelector"))
+
+	// Test validating webhook rejects field selectors that cannot be parsed
+	badFields := newComposable(`"fieldSelector": "metadata.name"`)
+	err = badFields.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.data.hosts.getValueFrom.fieldSelector"))
+}
+
+func TestAdmissionControlDryRun(t *testing.T) {
+	template := []byte(`{
+		"apiVersion": "apps/v1",
+		"kind": "Deployment",
+		"metadata": {
+		   "name": "dryrun"
+		 },
+		"spec": {
+		 "replicas": {
+		  "getValueFrom": {
+		   "kind": "ConfigMap",
+		   "name": "myconfigmap",
+		   "path": "{.data.replicas}",
+		   "format-transformers": ["StringToInt"]
+		   }
+		  },
+		 "paused": "${paused}",
+		 "selector": {"matchLabels": {"app": "dryrun"}},
+		 "template": {
+		  "metadata": {"labels": {"app": {"getValueFrom": {"kind": "ConfigMap", "name": "myconfigmap", "path": "{.data.app}"}}}},
+		  "spec": {"containers": [{"name": "app", "image": "nginx", "ports": [{"containerPort": "${port}"}]}]}
+		  }
+		 }
+		}`)
+	comp := &Composable{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Composable",
+			APIVersion: GroupVersion.String(),
+		},
+		Spec: ComposableSpec{
+			Template: &runtime.RawExtension{Raw: template},
+			Refs: map[string]runtime.RawExtension{
+				"paused": {Raw: []byte(`{"kind": "ConfigMap", "name": "myconfigmap", "path": "{.data.paused}"}`)},
+				"port":   {Raw: []byte(`{"kind": "ConfigMap", "name": "myconfigmap", "path": "{.data.port}"}`)},
+			},
+		},
+	}
+
+	object := func(properties map[string]spec.Schema) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"object"}, Properties: properties}}
+	}
+	typed := func(typ string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{typ}}}
+	}
+	ref := func(name string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef(schemaRefPrefix + name)}}
+	}
+	stringMap := spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"object"},
+		AdditionalProperties: &spec.SchemaOrBool{Allows: true, Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"string"}}}}}}
+	port := object(map[string]spec.Schema{"containerPort": typed("integer")})
+	container := object(map[string]spec.Schema{
+		"name":  typed("string"),
+		"image": typed("string"),
+		"ports": {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"array"}, Items: &spec.SchemaOrArray{Schema: &port}}},
+	})
+	deployment := object(map[string]spec.Schema{
+		"apiVersion": typed("string"),
+		"kind":       typed("string"),
+		"metadata":   ref("ObjectMeta"),
+		"spec": object(map[string]spec.Schema{
+			"replicas": typed("integer"),
+			"paused":   typed("boolean"),
+			"selector": object(map[string]spec.Schema{"matchLabels": stringMap}),
+			"template": object(map[string]spec.Schema{
+				"metadata": {SchemaProps: spec.SchemaProps{AllOf: []spec.Schema{ref("ObjectMeta")}}},
+				"spec":     object(map[string]spec.Schema{"containers": {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"array"}, Items: &spec.SchemaOrArray{Schema: &container}}}}),
+			}),
+		}),
+	})
+	objectMeta := object(map[string]spec.Schema{"name": typed("string"), "namespace": typed("string"), "labels": stringMap})
+	schemas := fakeSchemas{{Group: "apps", Version: "v1", Kind: "Deployment"}: {
+		schema:     &deployment,
+		components: map[string]*spec.Schema{"ObjectMeta": &objectMeta},
+	}}
+
+	g := gomega.NewGomegaWithT(t)
+	defer func() { webhookClients = nil }()
+
+	// Test validating webhook replaces unresolved object references by placeholders of the schema types
+	cl := &invalidObjects{}
+	webhookClients = &apiServerClients{client: cl, resolver: unresolvedRefs{}, schemas: schemas}
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.dryRun).NotTo(gomega.BeNil())
+	g.Expect(cl.dryRun.GetNamespace()).To(gomega.Equal("default"))
+	g.Expect(cl.dryRun.Object["spec"]).To(gomega.HaveKeyWithValue("replicas", int64(1)))
+	g.Expect(cl.dryRun.Object["spec"]).To(gomega.HaveKeyWithValue("paused", false))
+	g.Expect(cl.dryRun.GetName()).To(gomega.Equal("dryrun"))
+	labels, _, _ := unstructured.NestedStringMap(cl.dryRun.Object, "spec", "template", "metadata", "labels")
+	g.Expect(labels).To(gomega.HaveKeyWithValue("app", placeholderString))
+	containers, _, _ := unstructured.NestedSlice(cl.dryRun.Object, "spec", "template", "spec", "containers")
+	g.Expect(containers[0]).To(gomega.HaveKeyWithValue("ports", []interface{}{map[string]interface{}{"containerPort": int64(1)}}))
+
+	// Test validating webhook ignores the dry-run errors of the placeholders, and reports the other errors
+	cl.causes = []metav1.StatusCause{
+		{Type: metav1.CauseTypeFieldValueInvalid, Field: "spec.template.spec.containers[0].ports[0].containerPort", Message: "must be between 1 and 65535"},
+		{Type: metav1.CauseTypeFieldValueInvalid, Field: "spec.selector", Message: "selector does not match template labels"},
+	}
+	err := comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.spec.selector"))
+	g.Expect(err.Error()).NotTo(gomega.ContainSubstring("containerPort"))
+
+	// Test validating webhook dry-runs templates of kinds without schemas with string placeholders
+	cl.causes = nil
+	webhookClients.schemas = fakeSchemas{}
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.dryRun.Object["spec"]).To(gomega.HaveKeyWithValue("replicas", placeholderString))
+
+	// Test validating webhook patches existing objects, and forces the conflicts only as the controller does it
+	cl.existing = &unstructured.Unstructured{}
+	cl.existing.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	cl.existing.SetName("dryrun")
+	cl.existing.SetNamespace("default")
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.patchOpts).NotTo(gomega.ContainElement(client.ForceOwnership))
+	comp.Spec.ForceConflicts = true
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.patchOpts).To(gomega.ContainElement(client.ForceOwnership))
+	comp.Spec.ForceConflicts = false
+
+	// Test validating webhook rejects existing objects that the controller would not adopt
+	comp.Spec.AdoptionPolicy = AdoptionPolicyNever
+	err = comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.metadata.name"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("the adoption policy is Never"))
+	isController := true
+	cl.existing.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "Other", Name: "other", UID: "other", Controller: &isController}})
+	comp.Spec.AdoptionPolicy = ""
+	err = comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("is controlled by Other other"))
+	comp.Spec.AdoptionPolicy = AdoptionPolicyAlways
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.patchOpts).To(gomega.ContainElement(client.ForceOwnership))
+
+	// Test validating webhook updates its own objects without adoption
+	comp.Spec.AdoptionPolicy = AdoptionPolicyNever
+	comp.UID = "foo"
+	cl.existing.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: GroupVersion.String(), Kind: "Composable", Name: "foo", UID: "foo", Controller: &isController}})
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.patchOpts).NotTo(gomega.ContainElement(client.ForceOwnership))
+}
+
+func TestAdmissionControlSchema(t *testing.T) {
+	composable := func(template string) *Composable {
+		return &Composable{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Composable",
+				APIVersion: GroupVersion.String(),
+			},
+			Spec: ComposableSpec{
+				Template: &runtime.RawExtension{Raw: []byte(template)},
+				Refs: map[string]runtime.RawExtension{
+					"paused": {Raw: []byte(`{"kind": "ConfigMap", "name": "myconfigmap", "path": "{.data.paused}"}`)},
+				},
+			},
+		}
+	}
+
+	typed := func(typ string) spec.Schema {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{typ}}}
+	}
+	metadata := spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"object"},
+		Properties: map[string]spec.Schema{"name": typed("string"), "namespace": typed("string")}}}
+	deployment := spec.Schema{SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"object"}, Properties: map[string]spec.Schema{
+		"apiVersion": typed("string"),
+		"kind":       typed("string"),
+		"metadata":   metadata,
+		"spec": {SchemaProps: spec.SchemaProps{Type: spec.StringOrArray{"object"}, Properties: map[string]spec.Schema{
+			"replicas": typed("integer"),
+			"paused":   typed("boolean"),
+		}}},
+	}}}
+	resources := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			{Name: "deployments/scale", Kind: "Scale", Namespaced: true},
+		}},
+	}}}
+
+	g := gomega.NewGomegaWithT(t)
+	defer func() { webhookClients = nil }()
+	cl := &invalidObjects{}
+	webhookClients = &apiServerClients{
+		client:    cl,
+		resources: sdk.NewCachedResources(resources),
+		resolver:  unresolvedRefs{},
+		schemas: fakeSchemas{{Group: "apps", Version: "v1", Kind: "Deployment"}: {
+			schema: &deployment,
+		}},
+	}
+
+	// Test validating webhook accepts templates that conform to the schema, and ignores the placeholders types
+	comp := composable(`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "schema"}, "spec": {"replicas": 2, "paused": "${paused}"}}`)
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.dryRun).NotTo(gomega.BeNil())
+
+	// Test validating webhook caches the kinds, and discovers them again when a kind is not found
+	resources.ClearActions()
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(resources.Actions()).To(gomega.BeEmpty())
+	resources.Resources[1] = &metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: append(resources.Resources[1].APIResources,
+		metav1.APIResource{Name: "replicasets", Kind: "ReplicaSet", Namespaced: true})}
+	comp = composable(`{"apiVersion": "apps/v1", "kind": "ReplicaSet", "metadata": {"name": "schema"}}`)
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(resources.Actions()).To(gomega.HaveLen(1))
+
+	// Test validating webhook rejects API versions that are not served
+	cl.dryRun = nil
+	comp = composable(`{"apiVersion": "apps/v2", "kind": "Deployment", "metadata": {"name": "schema"}}`)
+	err := comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.apiVersion"))
+	g.Expect(cl.dryRun).To(gomega.BeNil())
+
+	// Test validating webhook rejects unknown kinds, and does not mistake subresources for kinds
+	comp = composable(`{"apiVersion": "apps/v1", "kind": "Scale", "metadata": {"name": "schema"}}`)
+	err = comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.kind"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("is not found"))
+
+	// Test validating webhook rejects cluster-scoped kinds
+	comp = composable(`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "schema"}}`)
+	err = comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("spec.template.kind"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("cluster-scoped"))
+
+	// Test validating webhook rejects values of wrong types, and unknown fields
+	comp = composable(`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "schema"}, "spec": {"replicas": "two", "pause": true}}`)
+	err = comp.validateComposable()
+	g.Expect(err).To(gomega.HaveOccurred())
+	var statusErr *apierrors.StatusError
+	g.Expect(errors.As(err, &statusErr)).To(gomega.BeTrue())
+	var fields []string
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(gomega.ConsistOf("spec.template.spec.replicas", "spec.template.spec.pause"))
+	g.Expect(cl.dryRun).To(gomega.BeNil())
+
+	// Test validating webhook validates the kind, but not the fields, of kinds without schemas
+	comp = composable(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "schema"}, "data": {"key": "value"}}`)
+	g.Expect(comp.validateComposable()).To(gomega.Succeed())
+	g.Expect(cl.dryRun).NotTo(gomega.BeNil())
+}
+
+// fakeSchemas provides the schemas of the kinds of the dry-run tests
+type fakeSchemas map[schema.GroupVersionKind]*kindSchema
+
+func (f fakeSchemas) kindSchema(ctx context.Context, gvk schema.GroupVersionKind) (*kindSchema, error) {
+	return f[gvk], nil
+}
+
+// unresolvedRefs is a resolver that cannot resolve object references, because their input objects do not exist
+type unresolvedRefs struct {
+	sdk.KubernetesResourceResolver
+}
+
+func (unresolvedRefs) ResolveObjectWithOptions(ctx context.Context, in, out interface{}, opts sdk.ResolveOptions) ([]sdk.InputObject, error) {
+	return nil, sdk.ErrObjectNotFound
+}
+
+// invalidObjects is a client that records the objects created or patched in dry-run mode, and rejects them with the
+// given causes. If the existing object is set, the objects are patched rather than created.
+type invalidObjects struct {
+	client.Client
+	causes    []metav1.StatusCause
+	dryRun    *unstructured.Unstructured
+	existing  *unstructured.Unstructured
+	patchOpts []client.PatchOption
+}
+
+func (c *invalidObjects) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.existing != nil {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Group: "apps", Resource: "deployments"}, obj.GetName())
+	}
+	c.dryRun = obj.(*unstructured.Unstructured)
+	return c.invalid()
+}
+
+func (c *invalidObjects) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	c.existing.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (c *invalidObjects) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.dryRun = obj.(*unstructured.Unstructured)
+	c.patchOpts = opts
+	return c.invalid()
+}
+
+func (c *invalidObjects) invalid() error {
+	if len(c.causes) == 0 {
+		return nil
+	}
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusUnprocessableEntity,
+		Reason:  metav1.StatusReasonInvalid,
+		Message: "Deployment is invalid",
+		Details: &metav1.StatusDetails{Causes: c.causes},
+	}}
 }
@@ -0,0 +1,227 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	// gvkExtension - the extension of OpenAPI schemas that lists the kinds that they describe
+	gvkExtension = "x-kubernetes-group-version-kind"
+	// intOrStringExtension - the extension of OpenAPI schemas of values that are either integers or strings
+	intOrStringExtension = "x-kubernetes-int-or-string"
+	// schemaRefPrefix - the prefix of the references to the schemas of an OpenAPI v3 document
+	schemaRefPrefix = "#/components/schemas/"
+)
+
+// kindSchema is the OpenAPI schema of a kind, with the schemas that it refers to
+type kindSchema struct {
+	schema     *spec.Schema
+	components map[string]*spec.Schema
+}
+
+// schemaSource provides the OpenAPI schemas of the kinds served by the API server
+type schemaSource interface {
+	// kindSchema returns the schema of the kind, or nil if the API server does not publish it
+	kindSchema(ctx context.Context, gvk schema.GroupVersionKind) (*kindSchema, error)
+}
+
+// openAPIV3Discovery is the list of the OpenAPI v3 documents of the API server, by group version
+type openAPIV3Discovery struct {
+	Paths map[string]struct {
+		// ServerRelativeURL includes a hash of the document, so a changed document has another URL
+		ServerRelativeURL string `json:"serverRelativeURL"`
+	} `json:"paths"`
+}
+
+// openAPIV3DiscoveryTTL - the time the list of the OpenAPI v3 documents is cached
+const openAPIV3DiscoveryTTL = time.Minute
+
+// openAPIV3Schemas reads the schemas of kinds from the OpenAPI v3 documents of their group versions.
+// The list of the documents is cached for openAPIV3DiscoveryTTL, and it is read again when a group version or a kind
+// is not found, e.g. after a CRD is created. The document of a group version is cached until its URL, which includes
+// a hash of the document, is changed.
+type openAPIV3Schemas struct {
+	client rest.Interface
+	// now returns the current time, it can be replaced by tests
+	now func() time.Time
+
+	lock sync.Mutex
+	// discovery is the cached list of the documents, read at discoveryTime
+	discovery     *openAPIV3Discovery
+	discoveryTime time.Time
+	// docs are the cached documents, by group version path
+	docs map[string]openAPIV3Document
+}
+
+// openAPIV3Document is a cached OpenAPI v3 document of a group version
+type openAPIV3Document struct {
+	url string
+	doc *spec3.OpenAPI
+}
+
+var _ schemaSource = &openAPIV3Schemas{}
+
+// kindSchema returns the schema of the kind, or nil if the API server does not publish it
+func (s *openAPIV3Schemas) kindSchema(ctx context.Context, gvk schema.GroupVersionKind) (*kindSchema, error) {
+	gvPath := "apis/" + gvk.GroupVersion().String()
+	if len(gvk.Group) == 0 {
+		gvPath = "api/" + gvk.Version
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, refresh := range []bool{false, true} {
+		discovery, refreshed, err := s.readDiscovery(ctx, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if gv, ok := discovery.Paths[gvPath]; ok {
+			doc, err := s.document(ctx, gvPath, gv.ServerRelativeURL)
+			if err != nil {
+				return nil, err
+			}
+			if doc.Components != nil {
+				for _, candidate := range doc.Components.Schemas {
+					if describesKind(candidate, gvk) {
+						return &kindSchema{schema: candidate, components: doc.Components.Schemas}, nil
+					}
+				}
+			}
+		}
+		if refreshed {
+			// the list of the documents is up to date
+			break
+		}
+	}
+	return nil, nil
+}
+
+// readDiscovery returns the cached list of the OpenAPI documents, or reads it if it is expired or if refresh is true.
+// It returns true if the list is read.
+func (s *openAPIV3Schemas) readDiscovery(ctx context.Context, refresh bool) (*openAPIV3Discovery, bool, error) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	if !refresh && s.discovery != nil && now().Sub(s.discoveryTime) < openAPIV3DiscoveryTTL {
+		return s.discovery, false, nil
+	}
+	data, err := s.client.Get().AbsPath("/openapi/v3").Do(ctx).Raw()
+	if err != nil {
+		return nil, false, err
+	}
+	discovery := &openAPIV3Discovery{}
+	if err := json.Unmarshal(data, discovery); err != nil {
+		return nil, false, err
+	}
+	s.discovery, s.discoveryTime = discovery, now()
+	return discovery, true, nil
+}
+
+// document returns the cached OpenAPI document of the group version, or reads it if its URL is changed
+func (s *openAPIV3Schemas) document(ctx context.Context, gvPath, url string) (*spec3.OpenAPI, error) {
+	if cached, ok := s.docs[gvPath]; ok && cached.url == url {
+		return cached.doc, nil
+	}
+	path, query, _ := strings.Cut(url, "?")
+	req := s.client.Get().AbsPath(path)
+	if hash, ok := strings.CutPrefix(query, "hash="); ok {
+		req = req.Param("hash", hash)
+	}
+	data, err := req.SetHeader("Accept", "application/json").Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	doc := &spec3.OpenAPI{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("cannot parse the OpenAPI document %s: %w", path, err)
+	}
+	if s.docs == nil {
+		s.docs = make(map[string]openAPIV3Document)
+	}
+	// the document replaces the previous document of the group version
+	s.docs[gvPath] = openAPIV3Document{url: url, doc: doc}
+	return doc, nil
+}
+
+// describesKind returns true if the schema is the schema of the kind
+func describesKind(s *spec.Schema, gvk schema.GroupVersionKind) bool {
+	kinds, ok := s.Extensions[gvkExtension].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, k := range kinds {
+		if m, ok := k.(map[string]interface{}); ok && m["group"] == gvk.Group && m["version"] == gvk.Version && m["kind"] == gvk.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve follows the reference of a schema, and its single allOf element, that OpenAPI v3 uses to describe a reference
+// with a default value or a description. It returns nil if the referred schema is unknown.
+// The kind schema is nil if it is unknown, then the schemas of its fields are unknown as well.
+func (k *kindSchema) resolve(s *spec.Schema) *spec.Schema {
+	if k == nil {
+		return nil
+	}
+	for s != nil {
+		if ref := s.Ref.String(); len(ref) > 0 {
+			s = k.components[strings.TrimPrefix(ref, schemaRefPrefix)]
+			continue
+		}
+		if len(s.AllOf) == 1 && len(s.Type) == 0 && len(s.Properties) == 0 {
+			s = &s.AllOf[0]
+			continue
+		}
+		return s
+	}
+	return nil
+}
+
+// property returns the schema of a property of an object, or nil if the schema does not define it
+func (k *kindSchema) property(s *spec.Schema, name string) *spec.Schema {
+	s = k.resolve(s)
+	if s == nil {
+		return nil
+	}
+	if prop, ok := s.Properties[name]; ok {
+		return k.resolve(&prop)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		return k.resolve(s.AdditionalProperties.Schema)
+	}
+	return nil
+}
+
+// item returns the schema of the items of an array, or nil if the schema does not define it
+func (k *kindSchema) item(s *spec.Schema) *spec.Schema {
+	s = k.resolve(s)
+	if s == nil || s.Items == nil || s.Items.Schema == nil {
+		return nil
+	}
+	return k.resolve(s.Items.Schema)
+}
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakerest "k8s.io/client-go/rest/fake"
+)
+
+func TestOpenAPIV3SchemasCache(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	statefulSet := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	job := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+
+	// the API server publishes the document of apps/v1, its kinds are changed with its hash
+	hash := "first"
+	kinds := []string{"Deployment"}
+	requests := map[string]int{}
+	cl := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			requests[req.URL.Path]++
+			var body string
+			switch req.URL.Path {
+			case "/openapi/v3":
+				body = fmt.Sprintf(`{"paths": {"apis/apps/v1": {"serverRelativeURL": "/openapi/v3/apis/apps/v1?hash=%s"}}}`, hash)
+			case "/openapi/v3/apis/apps/v1":
+				if req.URL.Query().Get("hash") != hash {
+					return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(&bytes.Buffer{})}, nil
+				}
+				schemas := ""
+				for i, kind := range kinds {
+					if i > 0 {
+						schemas += ","
+					}
+					schemas += fmt.Sprintf(`"io.k8s.api.apps.v1.%s": {"type": "object", "x-kubernetes-group-version-kind": [{"group": "apps", "version": "v1", "kind": "%s"}]}`, kind, kind)
+				}
+				body = fmt.Sprintf(`{"openapi": "3.0.0", "components": {"schemas": {%s}}}`, schemas)
+			default:
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(&bytes.Buffer{})}, nil
+			}
+			header := http.Header{}
+			header.Set("Content-Type", "application/json")
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+		}),
+	}
+	now := time.Now()
+	schemas := &openAPIV3Schemas{client: cl, now: func() time.Time { return now }}
+	ctx := context.TODO()
+
+	// Test the list of the documents and the documents are cached
+	for i := 0; i < 2; i++ {
+		s, err := schemas.kindSchema(ctx, deployment)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(s).NotTo(gomega.BeNil())
+	}
+	g.Expect(requests).To(gomega.Equal(map[string]int{"/openapi/v3": 1, "/openapi/v3/apis/apps/v1": 1}))
+
+	// Test the list of the documents is read again when a group version is not found
+	s, err := schemas.kindSchema(ctx, job)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(s).To(gomega.BeNil())
+	g.Expect(requests["/openapi/v3"]).To(gomega.Equal(2))
+
+	// Test the changed document replaces the cached document, when a kind is not found
+	hash, kinds = "second", []string{"Deployment", "StatefulSet"}
+	s, err = schemas.kindSchema(ctx, statefulSet)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(s).NotTo(gomega.BeNil())
+	g.Expect(requests).To(gomega.Equal(map[string]int{"/openapi/v3": 3, "/openapi/v3/apis/apps/v1": 2}))
+	g.Expect(schemas.docs).To(gomega.HaveLen(1))
+	g.Expect(schemas.docs["apis/apps/v1"].url).To(gomega.Equal("/openapi/v3/apis/apps/v1?hash=second"))
+
+	// Test the list of the documents expires
+	now = now.Add(openAPIV3DiscoveryTTL)
+	_, err = schemas.kindSchema(ctx, deployment)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(requests).To(gomega.Equal(map[string]int{"/openapi/v3": 4, "/openapi/v3/apis/apps/v1": 2}))
+}
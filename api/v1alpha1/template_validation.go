@@ -0,0 +1,242 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	sdk "github.com/composable-operator/composable/sdk"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/discovery"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// preserveUnknownFieldsExtension - the extension of OpenAPI schemas of objects that accept fields without schemas
+	preserveUnknownFieldsExtension = "x-kubernetes-preserve-unknown-fields"
+	// apiServerValidationTimeout - the timeout of the validation of the templates against the API server, it is below
+	// the 10 seconds timeout of the webhook, so the validation fails open if the API server is slow
+	apiServerValidationTimeout = 8 * time.Second
+)
+
+// webhookClients are the clients of the validating webhook to the API server. They are nil until SetupWebhookWithManager
+// is called, so templates are only validated statically, e.g. by unit tests.
+var webhookClients *apiServerClients
+
+// apiServerClients are the clients used to validate templates against the API server
+type apiServerClients struct {
+	// client creates and updates the underlying objects in dry-run mode
+	client client.Client
+	// resources discovers the underlying object kinds, it is shared with the resolver
+	resources *sdk.CachedResources
+	// resolver resolves the object references of the templates, if their input objects exist
+	resolver sdk.OptionsResolver
+	// schemas provides the schemas of the underlying objects kinds
+	schemas schemaSource
+}
+
+// newAPIServerClients returns the clients of the validating webhook to the API server of the manager
+func newAPIServerClients(mgr ctrl.Manager) (*apiServerClients, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	resources := sdk.NewCachedResources(discoveryClient)
+	return &apiServerClients{
+		client:    mgr.GetClient(),
+		resources: resources,
+		resolver: sdk.KubernetesResourceResolver{
+			Client:          mgr.GetClient(),
+			ResourcesClient: resources,
+		},
+		schemas: &openAPIV3Schemas{client: discoveryClient.RESTClient()},
+	}, nil
+}
+
+// validateWithAPIServer validates a template, that is valid statically, against the API server: its kind should be
+// a namespaced kind served by the API server, the template should conform to the schema of the kind, and the
+// underlying object should pass a dry-run. The object references of the template are replaced by placeholders of the
+// types defined by the schema, and the errors of the fields set by placeholders are ignored.
+func (r *Composable) validateWithAPIServer(ctx context.Context, m map[string]interface{}, refs map[string]interface{}, fieldpath *field.Path) field.ErrorList {
+	if webhookClients == nil {
+		return nil
+	}
+	template := runtime.DeepCopyJSON(m)
+	if metadata, ok := template[sdk.Metadata].(map[string]interface{}); ok && metadata[sdk.Namespace] == nil {
+		// the controller creates the underlying objects in the namespace of the Composable
+		metadata[sdk.Namespace] = r.Namespace
+	}
+	gvk := (&unstructured.Unstructured{Object: template}).GroupVersionKind()
+	if allErrs := r.validateKind(gvk, fieldpath); len(allErrs) > 0 {
+		return allErrs
+	}
+
+	s, err := webhookClients.schemas.kindSchema(ctx, gvk)
+	if err != nil {
+		composablelog.Info("validate without schema", "name", r.Name, "kind", gvk, "err", err.Error())
+	}
+	var placeholders []*field.Path
+	substituted, _ := s.substitute(runtime.DeepCopyJSON(template), s.root(), fieldpath, &placeholders).(map[string]interface{})
+	if allErrs := r.validateSchema(substituted, s, placeholders, fieldpath); len(allErrs) > 0 {
+		return allErrs
+	}
+	return r.dryRun(ctx, template, substituted, refs, placeholders, fieldpath)
+}
+
+// validateKind validates that the kind of the template is served by the API server, and that it is namespaced,
+// because the underlying objects are created in the namespace of the Composable. The kinds are cached, and the cache
+// is invalidated when the API version or the kind are not found, so the kinds of new CRDs are found.
+// The kind is not validated if the API server cannot be queried.
+func (r *Composable) validateKind(gvk schema.GroupVersionKind, fieldpath *field.Path) field.ErrorList {
+	if webhookClients.resources == nil {
+		return nil
+	}
+	apiVersion, kind := gvk.ToAPIVersionAndKind()
+	resource, err := lookupKind(apiVersion, kind)
+	if resource == nil && (err == nil || apierrors.IsNotFound(err)) {
+		webhookClients.resources.Invalidate()
+		resource, err = lookupKind(apiVersion, kind)
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		return field.ErrorList{field.Invalid(fieldpath.Child("apiVersion"), apiVersion, fmt.Sprintf("API version %s is not served by the API server", apiVersion))}
+	case err != nil:
+		composablelog.Info("validate kind", "name", r.Name, "kind", gvk, "err", err.Error())
+		return nil
+	case resource == nil:
+		return field.ErrorList{field.Invalid(fieldpath.Child("kind"), kind, fmt.Sprintf("kind %s is not found in API version %s", kind, apiVersion))}
+	case !resource.Namespaced:
+		return field.ErrorList{field.Invalid(fieldpath.Child("kind"), kind, fmt.Sprintf("kind %s is cluster-scoped, only namespaced objects can be created in the namespace of the Composable", kind))}
+	}
+	return nil
+}
+
+// lookupKind returns the API resource of the kind, or nil if the API version does not have the kind
+func lookupKind(apiVersion, kind string) (*metav1.APIResource, error) {
+	resources, err := webhookClients.resources.ServerResourcesForGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	for i, resource := range resources.APIResources {
+		if resource.Kind == kind && !strings.Contains(resource.Name, "/") {
+			return &resources.APIResources[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// validateSchema validates the template against the OpenAPI v3 schema of its kind, if the API server publishes it.
+// The errors of the fields set by placeholders are ignored.
+func (r *Composable) validateSchema(template map[string]interface{}, s *kindSchema, placeholders []*field.Path, fieldpath *field.Path) field.ErrorList {
+	if s == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	expanded := s.expand(s.schema, template, fieldpath, &allErrs)
+	validator := validate.NewSchemaValidator(expanded, nil, "", strfmt.Default)
+	for _, err := range validation.ValidateCustomResource(fieldpath, template, validator) {
+		if !setByPlaceholder(field.NewPath(err.Field), placeholders) {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return allErrs
+}
+
+// root returns the schema of the kind, or nil if it is unknown
+func (k *kindSchema) root() *spec.Schema {
+	if k == nil {
+		return nil
+	}
+	return k.schema
+}
+
+// expand returns a copy of the schema of a value, where the references to other schemas are replaced by the referred
+// schemas. Only the schemas of the fields of the value are expanded, so recursive schemas are expanded as deep as the
+// value is. The fields of the value that are not defined by an object schema, that does not accept unknown fields, are
+// reported as errors.
+func (k *kindSchema) expand(s *spec.Schema, value interface{}, fldPath *field.Path, allErrs *field.ErrorList) *spec.Schema {
+	s = k.resolve(s)
+	if s == nil {
+		return &spec.Schema{}
+	}
+	expanded := *s
+	expanded.Ref = spec.Ref{}
+	// the validator does not support references, and the compositions of references
+	expanded.AllOf, expanded.AnyOf, expanded.OneOf, expanded.Not = nil, nil, nil, nil
+	expanded.Definitions = nil
+	if s.Extensions[intOrStringExtension] == true {
+		expanded.Type = nil
+	}
+	if !strfmt.Default.ContainsName(s.Format) {
+		expanded.Format = ""
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		expanded.Properties = make(map[string]spec.Schema, len(v))
+		expanded.PatternProperties = nil
+		for _, key := range keys {
+			prop := k.property(s, key)
+			if prop == nil && len(s.Properties) > 0 && s.AdditionalProperties == nil && s.Extensions[preserveUnknownFieldsExtension] != true {
+				*allErrs = append(*allErrs, field.NotSupported(fldPath.Child(key), key, sortedProperties(s)))
+				continue
+			}
+			expanded.Properties[key] = *k.expand(prop, v[key], fldPath.Child(key), allErrs)
+		}
+		// all fields of the value are properties of the expanded schema
+		if expanded.AdditionalProperties != nil && expanded.AdditionalProperties.Schema != nil {
+			expanded.AdditionalProperties = nil
+		}
+	case []interface{}:
+		items := make([]spec.Schema, 0, len(v))
+		for i, item := range v {
+			items = append(items, *k.expand(k.item(s), item, fldPath.Index(i), allErrs))
+		}
+		expanded.Items = &spec.SchemaOrArray{Schemas: items}
+		expanded.AdditionalItems = nil
+	default:
+		expanded.Properties, expanded.AdditionalProperties, expanded.PatternProperties = nil, nil, nil
+		expanded.Items, expanded.AdditionalItems = nil, nil
+	}
+	return &expanded
+}
+
+// sortedProperties returns the sorted names of the properties of an object schema
+func sortedProperties(s *spec.Schema) []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
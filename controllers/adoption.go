@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
+)
+
+// adoptionRefusedError - the adoption policy of the Composable does not allow to take over an existing underlying object
+type adoptionRefusedError struct {
+	message string
+}
+
+func (e *adoptionRefusedError) Error() string {
+	return e.message
+}
+
+// adoptionPolicy returns the adoption policy of the Composable, IfUnowned if it is not defined
+func adoptionPolicy(compInstance *ibmcloudv1alpha1.Composable) string {
+	if len(compInstance.Spec.AdoptionPolicy) == 0 {
+		return ibmcloudv1alpha1.AdoptionPolicyIfUnowned
+	}
+	return compInstance.Spec.AdoptionPolicy
+}
+
+// adoptUnderlyingObject decides whether the Composable may take over an existing underlying object that it does not
+// control, according to its adoption policy. It returns true if the object is adopted, and an *adoptionRefusedError
+// if the adoption is refused. With the Always policy, the controller reference of another owner is removed from the object.
+func (r *ComposableReconciler) adoptUnderlyingObject(ctx context.Context, compInstance *ibmcloudv1alpha1.Composable, underlyingObj *unstructured.Unstructured) (bool, error) {
+	logger := log.FromContext(ctx)
+	owner := metav1.GetControllerOf(underlyingObj)
+	if owner != nil && owner.UID == compInstance.UID {
+		return false, nil
+	}
+	policy := adoptionPolicy(compInstance)
+	objName := fmt.Sprintf("%s %s/%s", underlyingObj.GetKind(), underlyingObj.GetNamespace(), underlyingObj.GetName())
+	switch {
+	case policy == ibmcloudv1alpha1.AdoptionPolicyNever:
+		return false, &adoptionRefusedError{fmt.Sprintf("%s already exists, and the adoption policy is %s", objName, policy)}
+	case owner == nil:
+		logger.Info("Adopt underlying object", "object", objName, "adoptionPolicy", policy)
+		return true, nil
+	case policy == ibmcloudv1alpha1.AdoptionPolicyIfUnowned:
+		return false, &adoptionRefusedError{fmt.Sprintf("%s is controlled by %s %s, and the adoption policy is %s", objName, owner.Kind, owner.Name, policy)}
+	}
+
+	logger.Info("Take over underlying object", "object", objName, "owner", owner.Name, "adoptionPolicy", policy)
+	owners := underlyingObj.GetOwnerReferences()
+	kept := make([]metav1.OwnerReference, 0, len(owners))
+	for _, ref := range owners {
+		if ref.UID != owner.UID {
+			kept = append(kept, ref)
+		}
+	}
+	patch := client.MergeFrom(underlyingObj.DeepCopy())
+	underlyingObj.SetOwnerReferences(kept)
+	if err := r.Patch(ctx, underlyingObj, patch); err != nil {
+		return false, err
+	}
+	return true, nil
+}
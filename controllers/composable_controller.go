@@ -17,17 +17,25 @@ limitations under the License.
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -50,6 +58,12 @@ const (
 	status         = "status"
 	state          = "state"
 	controllerName = "Composable-controller"
+	templateKey    = "template"
+	templatesKey   = "templates"
+	refsKey        = "refs"
+
+	// fieldManager - the field manager name used to apply underlying objects
+	fieldManager = "composable"
 
 	// FailedStatus composable status
 	FailedStatus = "Failed"
@@ -59,18 +73,40 @@ const (
 
 	// OnlineStatus - indicates that Composable successfully created underlying objects
 	OnlineStatus = "Online"
+
+	// notReadyRequeueDelay - the delay of the next reconciliation of a Composable waiting for its input objects to be ready.
+	// The input objects are watched as well, so the Composable is usually reconciled as soon as they are ready.
+	notReadyRequeueDelay = 30 * time.Second
 )
 
+// serverManagedMetadata lists metadata fields that are set by the API server
+var serverManagedMetadata = []string{
+	"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+	"deletionGracePeriodSeconds", "managedFields", "selfLink",
+}
+
 // ComposableReconciler reconciles a Composable object
 type ComposableReconciler struct {
 	client.Client
 	Scheme     *runtime.Scheme
 	Controller controller.Controller
 	Resolver   sdk.ResolveObject
+	// Recorder records the events of Composables and their underlying objects, events are not recorded if it is nil
+	Recorder record.EventRecorder
+
+	// inputWatches holds the input object kinds that are watched by the Controller
+	inputWatches map[schema.GroupVersionKind]bool
+	// underlyingWatches holds the underlying object kinds that are watched by the Controller
+	underlyingWatches map[schema.GroupVersionKind]bool
+	watchesLock       sync.Mutex
+	// states holds the states of the Composables, that are reported as metrics
+	states stateTracker
 }
 
 type ReconcilerOptions struct {
 	QueriesPerSecond float32
+	// EventsInterval is the minimal interval between identical events of an object, 5 minutes if it is not set
+	EventsInterval time.Duration
 }
 
 // ManagerSettableReconciler - a Reconciler that can be added to a Manager
@@ -84,11 +120,13 @@ func NewReconciler(mgr ctrl.Manager, opts ReconcilerOptions) ManagerSettableReco
 	cfg := mgr.GetConfig()
 	cfg.QPS = opts.QueriesPerSecond
 	return &ComposableReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: newRateLimitedRecorder(mgr.GetEventRecorderFor(controllerName), opts.EventsInterval),
 		Resolver: sdk.KubernetesResourceResolver{
-			Client:          mgr.GetClient(),
-			ResourcesClient: discovery.NewDiscoveryClientForConfigOrDie(cfg),
+			Client: mgr.GetClient(),
+			// the discovered API resources are cached, the cache is dropped when CRDs are changed
+			ResourcesClient: sdk.NewCachedResources(countingDiscovery{discovery.NewDiscoveryClientForConfigOrDie(cfg)}),
 		},
 	}
 }
@@ -104,6 +142,8 @@ func (r *ComposableReconciler) setController(controller controller.Controller) {
 // +kubebuilder:rbac:groups=ibmcloud.ibm.com,resources=composables,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=ibmcloud.ibm.com,resources=composables/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=ibmcloud.ibm.com,resources=composables/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 func (r *ComposableReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("composable", req.NamespacedName)
 
@@ -117,6 +157,7 @@ func (r *ComposableReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			// Object not found, return.
 			// For additional cleanup logic use finalizers.
 			logger.Info("Reconciled object is not found, return", "request", req)
+			r.states.set(req.NamespacedName, "")
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -124,18 +165,28 @@ func (r *ComposableReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	generation := compInstance.Generation
 	status := ibmcloudv1alpha1.ComposableStatus{}
+	// the conditions are copied, so their last transition times are kept
+	status.Conditions = append([]metav1.Condition(nil), compInstance.Status.Conditions...)
 	defer func() {
+		if status.State == OnlineStatus && compInstance.Status.State == OnlineStatus {
+			// keep the time when the Composable became online
+			status.Message = compInstance.Status.Message
+		}
 		if len(status.Message) == 0 {
 			status.Message = time.Now().Format(time.RFC850)
 		}
 		// Set Composable object Status
-		if len(status.State) > 0 &&
-			((status.State != OnlineStatus && !reflect.DeepEqual(status, compInstance.Status)) ||
-				status.State == OnlineStatus && compInstance.Status.State != OnlineStatus) {
+		if len(status.State) > 0 {
+			status.ObservedGeneration = generation
+			setSummaryConditions(&status, generation)
+			r.states.set(req.NamespacedName, status.State)
+			if reflect.DeepEqual(status, compInstance.Status) {
+				return
+			}
 			logger.V(1).Info("Set status", "desired status", status, "object", req)
-			compInstance.Status.State = status.State
-			compInstance.Status.Message = status.Message
+			compInstance.Status = status
 			if err := r.Status().Update(context.Background(), compInstance); err != nil {
 				logger.Info("Error in Update", "request", err.Error())
 				logger.Error(err, "Update status", "desired status", status, "object", req, "compInstance", compInstance)
@@ -143,44 +194,139 @@ func (r *ComposableReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}()
 
+	if !compInstance.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, compInstance, &status)
+	}
+	if err := r.updateFinalizer(ctx, compInstance); err != nil {
+		logger.Error(err, "Update finalizer returned", "object", req)
+		return ctrl.Result{}, err
+	}
+
+	if suspended(compInstance) {
+		logger.Info("Reconciliation is suspended", "request", req)
+		// the templates are neither resolved nor applied, so the last observed state is kept
+		status.State, status.Message = compInstance.Status.State, compInstance.Status.Message
+		status.Objects, status.Inputs = compInstance.Status.Objects, compInstance.Status.Inputs
+		if len(status.State) == 0 {
+			status.State, status.Message = PendingStatus, "The reconciliation is suspended"
+		}
+		setCondition(&status, generation, ibmcloudv1alpha1.ConditionSuspended, metav1.ConditionTrue, ibmcloudv1alpha1.ReasonSuspended, "The templates are neither resolved nor applied")
+		return ctrl.Result{}, nil
+	}
+	if meta.FindStatusCondition(status.Conditions, ibmcloudv1alpha1.ConditionSuspended) != nil {
+		setCondition(&status, generation, ibmcloudv1alpha1.ConditionSuspended, metav1.ConditionFalse, ibmcloudv1alpha1.ReasonResumed, "The reconciliation is resumed")
+	}
+
 	// If Status is not set, set it to Pending
 	if reflect.DeepEqual(compInstance.Status, ibmcloudv1alpha1.ComposableStatus{}) {
 		status.State = PendingStatus
 		status.Message = "Creating resource"
 	}
 
-	object, err := r.toJSONFromRaw(ctx, compInstance.Spec.Template)
-	if err != nil {
-		// we don't print the error, it was done in toJSONFromRaw
-		status.State = FailedStatus
-		status.Message = err.Error()
-		// we cannot return the error, because retries do not help
+	templates := compInstance.Spec.AllTemplates()
+	if len(templates) == 0 {
+		err := fmt.Errorf("Failed: Composable defines neither template nor templates")
+		setFailed(&status, generation, ibmcloudv1alpha1.ConditionResolved, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
 		return ctrl.Result{}, nil
 	}
+	objects := make([]interface{}, 0, len(templates))
+	for _, template := range templates {
+		object, err := r.toJSONFromRaw(ctx, template)
+		if err != nil {
+			// we don't print the error, it was done in toJSONFromRaw
+			setFailed(&status, generation, ibmcloudv1alpha1.ConditionResolved, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
+			// we cannot return the error, because retries do not help
+			return ctrl.Result{}, nil
+		}
 
-	updated, err := r.updateObjectNamespace(ctx, object, compInstance.Namespace)
-	if err != nil {
-		return ctrl.Result{}, err
+		updated, err := r.updateObjectNamespace(ctx, object, compInstance.Namespace)
+		if err != nil {
+			setFailed(&status, generation, ibmcloudv1alpha1.ConditionResolved, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
+			return ctrl.Result{}, err
+		}
+		objects = append(objects, updated)
 	}
 
-	resource := &unstructured.Unstructured{}
-	resource.Object = make(map[string]interface{})
+	refs := make(map[string]interface{}, len(compInstance.Spec.Refs))
+	for name := range compInstance.Spec.Refs {
+		ref := compInstance.Spec.Refs[name]
+		if refs[name], err = r.toJSONFromRaw(ctx, &ref); err != nil {
+			setFailed(&status, generation, ibmcloudv1alpha1.ConditionResolved, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
+			return ctrl.Result{}, nil
+		}
+	}
 
-	err = r.Resolver.ResolveObject(context.TODO(), updated, &resource.Object)
+	// all templates are resolved at once, so they share the lookups of a single resolution
+	resolved := make(map[string]interface{})
+	resolutionStart := time.Now()
+	inputs, err := r.resolveObject(context.TODO(), templatesObject(objects, compInstance.Spec.Template != nil, compInstance.Namespace), refs, &resolved)
+	resolutionDuration.WithLabelValues(req.Namespace, req.Name).Observe(time.Since(resolutionStart).Seconds())
+	status.Inputs = toInputReferences(inputs)
+	// the underlying objects are not changed until the templates are resolved again
+	status.Objects = compInstance.Status.Objects
+	// input objects are watched even if the resolution failed, so the Composable is reconciled once they appear
+	if werr := r.watchInputs(ctx, compInstance, inputs); werr != nil {
+		setFailed(&status, generation, ibmcloudv1alpha1.ConditionResolved, ibmcloudv1alpha1.ReasonResolveError, werr)
+		return ctrl.Result{}, werr
+	}
 
+	if sdk.IsNotReady(err) {
+		logger.Info("Waiting for an input object to be ready", "reason", err.Error())
+		r.resolveFailedEvent(compInstance, err)
+		setPending(&status, generation, ibmcloudv1alpha1.ConditionResolved, resolveErrorReason(err), err)
+		return ctrl.Result{RequeueAfter: notReadyRequeueDelay}, nil
+	}
 	if err != nil {
-		status.Message = err.Error()
-		status.State = FailedStatus
+		r.resolveFailedEvent(compInstance, err)
+		setFailed(&status, generation, ibmcloudv1alpha1.ConditionResolved, resolveErrorReason(err), err)
 		if sdk.IsRefNotFound(err) {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil
 
 	}
-	// if createUnderlyingObject faces with errors, it will update the state
+	setCondition(&status, generation, ibmcloudv1alpha1.ConditionResolved, metav1.ConditionTrue, ibmcloudv1alpha1.ReasonResolved, "All object references are resolved")
+
+	resources := resolvedTemplates(resolved)
+	// if createUnderlyingObjects faces with errors, it will update the state
 	status.State = OnlineStatus
 	logger.Info("Finish reconcile loop", "request", req)
-	return ctrl.Result{}, r.createUnderlyingObject(ctx, *resource, compInstance, &status)
+	return ctrl.Result{}, r.createUnderlyingObjects(ctx, resources, compInstance, &status)
+}
+
+// suspended returns true if the reconciliation of the Composable is suspended by its spec or by the annotation
+func suspended(compInstance *ibmcloudv1alpha1.Composable) bool {
+	return compInstance.Spec.Suspend || compInstance.Annotations[ibmcloudv1alpha1.SuspendAnnotation] == "true"
+}
+
+// templatesObject wraps the templates into a single object, that can be resolved by the Resolver.
+// The object has the layout of a Composable, so resolution errors refer to the template fields of the Composable.
+func templatesObject(templates []interface{}, hasTemplate bool, namespace string) map[string]interface{} {
+	compSpec := map[string]interface{}{}
+	if hasTemplate {
+		compSpec[templateKey] = templates[0]
+		templates = templates[1:]
+	}
+	if len(templates) > 0 {
+		compSpec[templatesKey] = templates
+	}
+	return map[string]interface{}{
+		sdk.Metadata: map[string]interface{}{sdk.Namespace: namespace},
+		spec:         compSpec,
+	}
+}
+
+// resolvedTemplates returns the resolved templates of an object created by templatesObject
+func resolvedTemplates(resolved map[string]interface{}) []interface{} {
+	compSpec, _ := resolved[spec].(map[string]interface{})
+	var templates []interface{}
+	if template, ok := compSpec[templateKey]; ok {
+		templates = append(templates, template)
+	}
+	if others, ok := compSpec[templatesKey].([]interface{}); ok {
+		templates = append(templates, others...)
+	}
+	return templates
 }
 
 func (r *ComposableReconciler) updateObjectNamespace(ctx context.Context, object interface{}, composableNamespace string) (interface{}, error) {
@@ -210,24 +356,66 @@ func (r *ComposableReconciler) updateObjectNamespace(ctx context.Context, object
 	return object, nil
 }
 
+// createUnderlyingObjects creates or updates the underlying objects in order, and reports their states in the status.
+// Objects that follow a failed object are left pending.
+func (r *ComposableReconciler) createUnderlyingObjects(ctx context.Context, resources []interface{},
+	compInstance *ibmcloudv1alpha1.Composable,
+	status *ibmcloudv1alpha1.ComposableStatus,
+) error {
+	status.Objects = make([]ibmcloudv1alpha1.UnderlyingObjectStatus, 0, len(resources))
+	for i, object := range resources {
+		objMap, _ := object.(map[string]interface{})
+		resource := unstructured.Unstructured{Object: objMap}
+		objStatus := underlyingObjectStatus(resource, OnlineStatus, "")
+		if previous := previousObjectStatus(compInstance, &resource); previous != nil {
+			// the last applied template and drift are kept, unless the object is applied or drifts again
+			objStatus.TemplateHash, objStatus.DriftedFields, objStatus.LastDriftTime = previous.TemplateHash, previous.DriftedFields, previous.LastDriftTime
+		}
+		err := r.createUnderlyingObject(ctx, resource, compInstance, status, &objStatus)
+		if err != nil || status.State == FailedStatus {
+			objStatus.State, objStatus.Message = FailedStatus, status.Message
+			status.Objects = append(status.Objects, objStatus)
+			for _, next := range resources[i+1:] {
+				nextMap, _ := next.(map[string]interface{})
+				message := fmt.Sprintf("Waiting for %s %s", resource.GetKind(), resource.GetName())
+				status.Objects = append(status.Objects, underlyingObjectStatus(unstructured.Unstructured{Object: nextMap}, PendingStatus, message))
+			}
+			return err
+		}
+		status.Objects = append(status.Objects, objStatus)
+	}
+	return nil
+}
+
+// underlyingObjectStatus returns the status of an underlying object
+func underlyingObjectStatus(resource unstructured.Unstructured, state, message string) ibmcloudv1alpha1.UnderlyingObjectStatus {
+	return ibmcloudv1alpha1.UnderlyingObjectStatus{
+		APIVersion: resource.GetAPIVersion(),
+		Kind:       resource.GetKind(),
+		Name:       resource.GetName(),
+		State:      state,
+		Message:    message,
+	}
+}
+
+// createUnderlyingObject creates or updates an underlying object, and reports its applied template and drift in objStatus
 func (r *ComposableReconciler) createUnderlyingObject(ctx context.Context, resource unstructured.Unstructured,
 	compInstance *ibmcloudv1alpha1.Composable,
 	status *ibmcloudv1alpha1.ComposableStatus,
+	objStatus *ibmcloudv1alpha1.UnderlyingObjectStatus,
 ) error {
 	logger := log.FromContext(ctx)
 
 	name, err := getName(resource.Object)
 	if err != nil {
-		status.State = FailedStatus
-		status.Message = err.Error()
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
 		return nil
 	}
 	logger.V(1).Info("Resource name is: "+name, "comName", compInstance.Name)
 
 	namespace, err := sdk.GetNamespace(resource.Object)
 	if err != nil {
-		status.State = FailedStatus
-		status.Message = err.Error()
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
 		return nil
 	}
 	logger.V(1).Info("Resource namespace is: "+namespace, "comName", compInstance.Name)
@@ -236,8 +424,7 @@ func (r *ComposableReconciler) createUnderlyingObject(ctx context.Context, resou
 	if !ok {
 		err := fmt.Errorf("The template has no apiVersion")
 		logger.Error(err, "", "template", resource.Object, "comName", compInstance.Name)
-		status.State = FailedStatus
-		status.Message = err.Error()
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
 		return nil
 	}
 	logger.V(1).Info("Resource apiversion is: "+apiversion, "comName", compInstance.Name)
@@ -246,18 +433,20 @@ func (r *ComposableReconciler) createUnderlyingObject(ctx context.Context, resou
 	if !ok {
 		err := fmt.Errorf("The template has no kind")
 		logger.Error(err, "", "template", resource.Object, "comName", compInstance.Name)
-		status.State = FailedStatus
-		status.Message = err.Error()
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonInvalidTemplate, err)
 		return nil
 	}
 	logger.V(1).Info("Resource kind is: " + kind)
 
+	// status and server-managed metadata cannot be applied
+	removeServerManagedFields(&resource)
+
 	if err := controllerutil.SetControllerReference(compInstance, &resource, r.Scheme); err != nil {
 		logger.Error(err, "SetControllerReference returned error", "resource", resource, "comName", compInstance.Name)
-		status.State = FailedStatus
-		status.Message = err.Error()
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonApplyError, err)
 		return nil
 	}
+	hash := templateHash(&resource)
 	underlyingObj := &unstructured.Unstructured{}
 	underlyingObj.SetAPIVersion(apiversion)
 	underlyingObj.SetKind(kind)
@@ -267,46 +456,128 @@ func (r *ComposableReconciler) createUnderlyingObject(ctx context.Context, resou
 	if err != nil {
 		if errors.IsNotFound(err) {
 			logger.Info("Creating new underlying resource", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
-			err = r.Create(context.TODO(), &resource)
+			err = r.applyUnderlyingObject(ctx, &resource, compInstance, status, compInstance.Spec.ForceConflicts)
 			if err != nil {
 				logger.Error(err, "Cannot create new resource", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
-				status.State = FailedStatus
-				status.Message = err.Error()
-				return err
-			}
-
-			// add watcher
-			err = r.Controller.Watch(&source.Kind{Type: underlyingObj}, &handler.EnqueueRequestForOwner{
-				IsController: true,
-				OwnerType:    &ibmcloudv1alpha1.Composable{},
-			})
-			if err != nil {
-				logger.Error(err, "Cannot add watcher", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
-				status.State = FailedStatus
-				status.Message = err.Error()
+				r.underlyingEvent(compInstance, &resource, corev1.EventTypeWarning, eventReasonApplyFailed, "Cannot create the object: "+err.Error())
 				return err
 			}
+			r.underlyingEvent(compInstance, &resource, corev1.EventTypeNormal, eventReasonCreated, "The object is created")
+			objStatus.TemplateHash = hash
 		} else {
 			logger.Error(err, "Cannot get resource", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
-			status.State = FailedStatus
-			status.Message = err.Error()
+			setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonApplyError, err)
 			return err
 		}
 	} else {
-		// Update the found object and write the result back if there are any changes
-
-		if !reflect.DeepEqual(resource.Object[spec], underlyingObj.Object[spec]) {
-			underlyingObj.Object[spec] = resource.Object[spec]
-			// logger.Info("Updating underlying resource spec", "currentSpec", resource.Object[spec], "newSpec", underlyingObj.Object[spec], "resource", namespaced, "kind", kind, "apiVersion", apiversion)
-			err = r.Update(context.TODO(), underlyingObj)
+		adopted, err := r.adoptUnderlyingObject(ctx, compInstance, underlyingObj)
+		if err != nil {
+			if _, refused := err.(*adoptionRefusedError); refused {
+				logger.Info("Adoption of underlying resource is refused", "resource", namespaced, "kind", kind, "reason", err.Error())
+				r.underlyingEvent(compInstance, underlyingObj, corev1.EventTypeWarning, eventReasonAdoptionRefused, err.Error())
+				setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonAdoptionRefused, err)
+				// retries do not help, until the adoption policy or the owner of the object are changed
+				return nil
+			}
+			logger.Error(err, "Cannot adopt resource", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
+			setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonApplyError, err)
+			return err
+		}
+		// The template is applied to the found object if it has been changed since it was applied last time, even if
+		// no field drifted, e.g. when a field is removed from the template, so the apply removes it from the object.
+		// It is applied as well if the object is adopted, so it gets the owner reference. Other differences are drift,
+		// handled by the drift policy.
+		drifted := driftedFields(&resource, underlyingObj)
+		updated := adopted || hash != objStatus.TemplateHash
+		apply, corrected := updated, false
+		if !apply && len(drifted) > 0 && driftPolicy(compInstance) != ibmcloudv1alpha1.DriftPolicyIgnore {
+			corrected = driftPolicy(compInstance) == ibmcloudv1alpha1.DriftPolicyCorrect
+			if setDrift(objStatus, drifted) {
+				logger.Info("Underlying resource drifted", "resource", namespaced, "kind", kind, "fields", drifted, "driftPolicy", driftPolicy(compInstance))
+				r.recordDrift(compInstance, underlyingObj, drifted, corrected)
+			}
+			apply = corrected
+		}
+		if apply {
+			logger.Info("Applying underlying resource", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
+			// a drifted field is usually owned by the field manager that changed it, e.g. kubectl edit, so the correction
+			// takes its ownership back, otherwise the apply would fail with a conflict
+			force := compInstance.Spec.ForceConflicts || corrected ||
+				(adopted && adoptionPolicy(compInstance) == ibmcloudv1alpha1.AdoptionPolicyAlways)
+			err = r.applyUnderlyingObject(ctx, &resource, compInstance, status, force)
 			if err != nil {
-
-				status.State = FailedStatus
-				status.Message = err.Error()
+				logger.Error(err, "Cannot apply resource", "resource", namespaced, "kind", kind, "apiVersion", apiversion)
+				r.underlyingEvent(compInstance, underlyingObj, corev1.EventTypeWarning, eventReasonApplyFailed, "Cannot update the object: "+err.Error())
 				return err
 			}
+			// the correction of a drift is recorded by recordDrift
+			if updated {
+				r.underlyingEvent(compInstance, underlyingObj, corev1.EventTypeNormal, eventReasonUpdated, "The object is updated")
+			}
+			objStatus.TemplateHash = hash
 		}
 	}
+	// the underlying objects are watched, so their drift is detected as soon as they are changed
+	if err = r.watchUnderlyingObjects(ctx, compInstance, underlyingObj, status); err != nil {
+		return err
+	}
+	setCondition(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, metav1.ConditionTrue, ibmcloudv1alpha1.ReasonApplied, "The underlying object is created or updated")
+	return nil
+}
+
+// watchUnderlyingObjects registers a watch for the kind of the underlying object, that reconciles its owner Composable,
+// if the kind is not watched yet
+func (r *ComposableReconciler) watchUnderlyingObjects(ctx context.Context, compInstance *ibmcloudv1alpha1.Composable,
+	underlyingObj *unstructured.Unstructured, status *ibmcloudv1alpha1.ComposableStatus,
+) error {
+	r.watchesLock.Lock()
+	defer r.watchesLock.Unlock()
+	if r.underlyingWatches == nil {
+		r.underlyingWatches = make(map[schema.GroupVersionKind]bool)
+	}
+	gvk := underlyingObj.GroupVersionKind()
+	if r.underlyingWatches[gvk] {
+		return nil
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err := r.Controller.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &ibmcloudv1alpha1.Composable{},
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Cannot add watcher", "kind", underlyingObj.GetKind(), "apiVersion", underlyingObj.GetAPIVersion())
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, ibmcloudv1alpha1.ReasonApplyError, err)
+		return err
+	}
+	r.underlyingWatches[gvk] = true
+	dynamicWatches.WithLabelValues(watchUnderlying).Set(float64(len(r.underlyingWatches)))
+	r.event(compInstance, corev1.EventTypeNormal, eventReasonWatchRegistered, "Watching underlying objects of kind %s", gvk.String())
+	return nil
+}
+
+// applyUnderlyingObject applies the resolved template to the underlying object with server-side apply.
+// If force is true, the Composable takes the ownership of fields that are managed by other field managers.
+func (r *ComposableReconciler) applyUnderlyingObject(ctx context.Context, resource *unstructured.Unstructured,
+	compInstance *ibmcloudv1alpha1.Composable,
+	status *ibmcloudv1alpha1.ComposableStatus,
+	force bool,
+) error {
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	err := r.Patch(ctx, resource, client.Apply, opts...)
+	if err != nil {
+		reason := ibmcloudv1alpha1.ReasonApplyError
+		if errors.IsConflict(err) {
+			reason = ibmcloudv1alpha1.ReasonApplyConflict
+		}
+		applyOutcomes.WithLabelValues(reason).Inc()
+		setFailed(status, compInstance.Generation, ibmcloudv1alpha1.ConditionApplied, reason, err)
+		return err
+	}
+	applyOutcomes.WithLabelValues(ibmcloudv1alpha1.ReasonApplied).Inc()
 	return nil
 }
 
@@ -320,6 +591,27 @@ func (r *ComposableReconciler) toJSONFromRaw(ctx context.Context, content *runti
 	return data, nil
 }
 
+// removeServerManagedFields removes the status and metadata fields that are managed by the API server from the object
+func removeServerManagedFields(obj *unstructured.Unstructured) {
+	delete(obj.Object, status)
+	for _, field := range serverManagedMetadata {
+		unstructured.RemoveNestedField(obj.Object, sdk.Metadata, field)
+	}
+}
+
+// jsonEqual compares the JSON representations of two values, so numbers of different types are equal
+func jsonEqual(a, b interface{}) bool {
+	aJSON, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bJSON, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aJSON, bJSON)
+}
+
 func getName(obj map[string]interface{}) (string, error) {
 	metadata := obj[sdk.Metadata].(map[string]interface{})
 	if name, ok := metadata[sdk.Name]; ok {
@@ -340,8 +632,18 @@ func getState(obj map[string]interface{}) (string, error) {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ComposableReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// index Composables by their input objects, so changes of the input objects trigger reconciliation of dependent Composables
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ibmcloudv1alpha1.Composable{}, inputsIndexKey, indexInputs); err != nil {
+		return err
+	}
+
+	// CRDs are watched, so new input object kinds are discovered
+	crd := &metav1.PartialObjectMetadata{}
+	crd.SetGroupVersionKind(apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+
 	ctrl, err := ctrl.NewControllerManagedBy(mgr).
 		For(&ibmcloudv1alpha1.Composable{}).
+		Watches(&source.Kind{Type: crd}, handler.EnqueueRequestsFromMapFunc(r.crdToComposables)).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: viper.GetInt("max-concurrent-reconciles"),
 		}).Build(r)
@@ -17,14 +17,22 @@
 package controllers
 
 import (
+	"context"
+	"time"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
 	"github.com/composable-operator/composable/controllers/test"
 	sdk "github.com/composable-operator/composable/sdk"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var testContext test.TestContext
@@ -111,6 +119,13 @@ var _ = Describe("test Composable operations", func() {
 		Eventually(test.GetObject(testContext, &comp)).ShouldNot(BeNil())
 		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
 
+		By("validate status conditions")
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionReady)).Should(HaveField("Status", metav1.ConditionTrue))
+		Expect(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionResolved)()).Should(HaveField("Status", metav1.ConditionTrue))
+		Expect(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionApplied)()).Should(HaveField("Status", metav1.ConditionTrue))
+		Expect(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionDegraded)()).Should(HaveField("Status", metav1.ConditionFalse))
+		Expect(comp.Status.ObservedGeneration).Should(Equal(comp.Generation))
+
 		By("Get Output object")
 		groupVersionKind = schema.GroupVersionKind{Kind: "OutputValue", Version: "v1", Group: "test.ibmcloud.ibm.com"}
 		unstrObj.SetGroupVersionKind(groupVersionKind)
@@ -119,6 +134,11 @@ var _ = Describe("test Composable operations", func() {
 		testSpec, ok := unstrObj.Object[spec].(map[string]interface{})
 		Expect(ok).Should(BeTrue())
 
+		By("validate that the Output object is applied by the composable field manager")
+		Expect(unstrObj.GetManagedFields()).Should(ContainElement(And(
+			HaveField("Manager", fieldManager),
+			HaveField("Operation", metav1.ManagedFieldsOperationApply))))
+
 		By("copy intValue")
 		// We use Eventually so the controller will be able to update teh destination object
 		Expect(testSpec["intValue"]).Should(BeEquivalentTo(12))
@@ -148,6 +168,49 @@ var _ = Describe("test Composable operations", func() {
 		val, _ := sdk.Array2CSStringTransformer(strArray)
 		Expect(testSpec["stringJson2Value"]).Should(BeEquivalentTo(val))
 	})
+	It("Composable should report its input objects and propagate their changes", func() {
+		gvkIn := schema.GroupVersionKind{Kind: "InputValue", Version: "v1", Group: "test.ibmcloud.ibm.com"}
+		gvkOut := schema.GroupVersionKind{Kind: "OutputValue", Version: "v1", Group: "test.ibmcloud.ibm.com"}
+		objNamespacednameIn := types.NamespacedName{Namespace: "default", Name: "inputdata"}
+		objNamespacednameOut := types.NamespacedName{Namespace: testContext.Namespace(), Name: "comp-out"}
+
+		By("deploy input Object")
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+
+		By("deploy Composable object")
+		comp := test.LoadComposable(dataDir + "compCopy.yaml")
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		By("validate status inputs")
+		Eventually(test.GetStatusInputs(testContext, &comp)).Should(ContainElement(ibmcloudv1alpha1.InputObjectReference{
+			APIVersion: gvkIn.GroupVersion().String(),
+			Kind:       gvkIn.Kind,
+			Namespace:  objNamespacednameIn.Namespace,
+			Name:       objNamespacednameIn.Name,
+		}))
+
+		By("update input Object")
+		unstrIn := unstructured.Unstructured{}
+		unstrIn.SetGroupVersionKind(gvkIn)
+		Expect(test.GetUnstructuredObject(testContext, objNamespacednameIn, &unstrIn)()).Should(Succeed())
+		Expect(unstructured.SetNestedField(unstrIn.Object, int64(13), spec, "intValue")).Should(Succeed())
+		test.UpdateObject(testContext, &unstrIn, false, 0)
+
+		By("check that the change is propagated before the next resync")
+		unstrObj = unstructured.Unstructured{}
+		unstrObj.SetGroupVersionKind(gvkOut)
+		Eventually(func() (int64, error) {
+			if err := test.GetUnstructuredObject(testContext, objNamespacednameOut, &unstrObj)(); err != nil {
+				return int64(0), err
+			}
+			intValue, _, err := unstructured.NestedInt64(unstrObj.Object, spec, "intValue")
+			return intValue, err
+		}, SYNC_PERIOD/2).Should(Equal(int64(13)))
+	})
+
 	It("Composable should successfully update values of the output object", func() {
 		gvkIn := schema.GroupVersionKind{Kind: "InputValue", Version: "v1", Group: "test.ibmcloud.ibm.com"}
 		gvkOut := schema.GroupVersionKind{Kind: "OutputValue", Version: "v1", Group: "test.ibmcloud.ibm.com"}
@@ -232,6 +295,575 @@ var _ = Describe("test Composable operations", func() {
 	})
 })
 
+var _ = Describe("Composable objects with templates without spec", func() {
+	dataDir := "testdata/"
+
+	AfterEach(func() {
+		comp := test.LoadComposable(dataDir + "compConfigMap.yaml")
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+	})
+
+	It("Composable should propagate data and labels updates to a ConfigMap", func() {
+		gvkIn := schema.GroupVersionKind{Kind: "InputValue", Version: "v1", Group: "test.ibmcloud.ibm.com"}
+		objNamespacednameIn := types.NamespacedName{Namespace: "default", Name: "inputdata"}
+		objNamespacednameOut := types.NamespacedName{Namespace: testContext.Namespace(), Name: "comp-configmap"}
+
+		By("deploy input Object")
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+
+		By("deploy Composable object")
+		comp := test.LoadComposable(dataDir + "compConfigMap.yaml")
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		By("get the ConfigMap")
+		cm := &v1.ConfigMap{}
+		Eventually(func() error { return testContext.Client().Get(context.TODO(), objNamespacednameOut, cm) }).Should(Succeed())
+		Expect(cm.Data["stringValue"]).Should(Equal("Hello world"))
+		Expect(cm.Labels["app"]).Should(Equal("composable"))
+
+		By("update input Object")
+		unstrIn := unstructured.Unstructured{}
+		unstrIn.SetGroupVersionKind(gvkIn)
+		Expect(test.GetUnstructuredObject(testContext, objNamespacednameIn, &unstrIn)()).Should(Succeed())
+		Expect(unstructured.SetNestedField(unstrIn.Object, "Hello again", spec, "stringValue")).Should(Succeed())
+		test.UpdateObject(testContext, &unstrIn, false, 0)
+
+		By("check that the ConfigMap data is updated")
+		Eventually(func() (string, error) {
+			err := testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)
+			return cm.Data["stringValue"], err
+		}).Should(Equal("Hello again"))
+
+		By("update the Composable template labels")
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(&comp), &comp)).Should(Succeed())
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetLabels(map[string]string{"app": "composable", "tier": "backend"})
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.UpdateObject(testContext, &comp, false, 0)
+
+		By("check that the ConfigMap labels are updated")
+		Eventually(func() (map[string]string, error) {
+			err := testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)
+			return cm.Labels, err
+		}).Should(HaveKeyWithValue("tier", "backend"))
+
+		By("remove a label from the Composable template")
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(&comp), &comp)).Should(Succeed())
+		template = unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetLabels(map[string]string{"app": "composable"})
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.UpdateObject(testContext, &comp, false, 0)
+
+		By("check that the label is removed from the ConfigMap")
+		Eventually(func() (map[string]string, error) {
+			err := testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)
+			return cm.Labels, err
+		}).ShouldNot(HaveKey("tier"))
+		Expect(cm.Labels).Should(HaveKeyWithValue("app", "composable"))
+	})
+})
+
+var _ = Describe("Composable objects with several templates", func() {
+	dataDir := "testdata/"
+
+	AfterEach(func() {
+		comp := test.LoadComposable(dataDir + "compTemplates.yaml")
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+	})
+
+	It("Composable should create all underlying objects and report them in its status", func() {
+		By("deploy input Object")
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+
+		By("deploy Composable object")
+		comp := test.LoadComposable(dataDir + "compTemplates.yaml")
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		By("get the ConfigMaps")
+		first := &v1.ConfigMap{}
+		Expect(testContext.Client().Get(context.TODO(), types.NamespacedName{Namespace: testContext.Namespace(), Name: "comp-configmap-first"}, first)).Should(Succeed())
+		Expect(first.Data["stringValue"]).Should(Equal("Hello world"))
+		Expect(metav1.IsControlledBy(first, &comp)).Should(BeTrue())
+		second := &v1.ConfigMap{}
+		Expect(testContext.Client().Get(context.TODO(), types.NamespacedName{Namespace: testContext.Namespace(), Name: "comp-configmap-second"}, second)).Should(Succeed())
+		Expect(second.Data["intValue"]).Should(Equal("12"))
+		Expect(metav1.IsControlledBy(second, &comp)).Should(BeTrue())
+
+		By("validate the status of the underlying objects")
+		objects := test.GetStatusObjects(testContext, &comp)()
+		Expect(objects).Should(HaveLen(2))
+		Expect(objects[0]).Should(And(HaveField("Kind", "ConfigMap"), HaveField("Name", "comp-configmap-first"), HaveField("State", OnlineStatus)))
+		Expect(objects[1]).Should(And(HaveField("Kind", "ConfigMap"), HaveField("Name", "comp-configmap-second"), HaveField("State", OnlineStatus)))
+	})
+})
+
+var _ = Describe("Composable deletion policies", func() {
+	dataDir := "testdata/"
+	objNamespacednameOut := types.NamespacedName{Name: "comp-configmap-deletion"}
+
+	BeforeEach(func() {
+		objNamespacednameOut.Namespace = testContext.Namespace()
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+	})
+
+	AfterEach(func() {
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+		test.DeleteObject(testContext, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}, false)
+	})
+
+	deploy := func(policy string) *ibmcloudv1alpha1.Composable {
+		comp := test.LoadComposable(dataDir + "compConfigMap.yaml")
+		comp.Spec.DeletionPolicy = policy
+		// the underlying objects are not garbage collected by envtest, so every test uses its own ConfigMap
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetName(objNamespacednameOut.Name)
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+		Eventually(func() ([]string, error) {
+			err := testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(&comp), &comp)
+			return comp.Finalizers, err
+		}).Should(ContainElement(composableFinalizer))
+		return &comp
+	}
+
+	It("Composable with the Orphan deletion policy should remove its owner reference from the underlying object", func() {
+		comp := deploy(ibmcloudv1alpha1.DeletionPolicyOrphan)
+
+		By("delete the Composable")
+		test.DeleteInNs(testContext, comp, false)
+		Eventually(test.GetObject(testContext, comp)).Should(BeNil())
+
+		By("check that the ConfigMap is orphaned")
+		cm := &v1.ConfigMap{}
+		Expect(testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)).Should(Succeed())
+		Expect(cm.OwnerReferences).Should(BeEmpty())
+	})
+
+	It("Composable with the Orphan deletion policy should orphan the objects of its templates that are missing in its status", func() {
+		comp := deploy(ibmcloudv1alpha1.DeletionPolicyOrphan)
+
+		By("suspend the Composable and clear the objects of its status")
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(comp), comp)).Should(Succeed())
+		comp.SetAnnotations(map[string]string{ibmcloudv1alpha1.SuspendAnnotation: "true"})
+		test.UpdateObject(testContext, comp, false, 0)
+		Eventually(test.GetStatusCondition(testContext, comp, ibmcloudv1alpha1.ConditionSuspended)).Should(HaveField("Status", metav1.ConditionTrue))
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(comp), comp)).Should(Succeed())
+		comp.Status.Objects = nil
+		Expect(testContext.Client().Status().Update(context.TODO(), comp)).Should(Succeed())
+
+		By("delete the Composable")
+		test.DeleteInNs(testContext, comp, false)
+		Eventually(test.GetObject(testContext, comp)).Should(BeNil())
+
+		By("check that the ConfigMap is orphaned")
+		cm := &v1.ConfigMap{}
+		Expect(testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)).Should(Succeed())
+		Expect(cm.OwnerReferences).Should(BeEmpty())
+	})
+
+	It("Composable with the Retain deletion policy should be deleted after the annotation is set", func() {
+		comp := deploy(ibmcloudv1alpha1.DeletionPolicyRetain)
+
+		By("delete the Composable")
+		test.DeleteInNs(testContext, comp, false)
+		Eventually(test.GetStatusMessage(testContext, comp)).Should(ContainSubstring(ibmcloudv1alpha1.AllowDeletionAnnotation))
+		Consistently(test.GetObject(testContext, comp)).ShouldNot(BeNil())
+
+		By("allow the deletion")
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(comp), comp)).Should(Succeed())
+		comp.SetAnnotations(map[string]string{ibmcloudv1alpha1.AllowDeletionAnnotation: "true"})
+		test.UpdateObject(testContext, comp, false, 0)
+		Eventually(test.GetObject(testContext, comp)).Should(BeNil())
+	})
+})
+
+var _ = Describe("Composable adoption policies", func() {
+	dataDir := "testdata/"
+	objNamespacednameOut := types.NamespacedName{Name: "comp-configmap-adoption"}
+	var comp ibmcloudv1alpha1.Composable
+
+	BeforeEach(func() {
+		objNamespacednameOut.Namespace = testContext.Namespace()
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+	})
+
+	AfterEach(func() {
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+		test.DeleteObject(testContext, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}, false)
+	})
+
+	// deploy creates the ConfigMap with the given owner, and then the Composable of the ConfigMap
+	deploy := func(policy string, owner *metav1.OwnerReference) {
+		cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}
+		if owner != nil {
+			cm.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		test.CreateObject(testContext, cm, false, 0)
+
+		comp = test.LoadComposable(dataDir + "compConfigMap.yaml")
+		comp.Name = "to-configmap-adoption"
+		comp.Spec.AdoptionPolicy = policy
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetName(objNamespacednameOut.Name)
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.PostInNs(testContext, &comp, false, 0)
+	}
+	controller := func() *metav1.OwnerReference {
+		isController := true
+		return &metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "8bbd6c7a-7d0b-4c6b-9f5e-2a3d1c1e0f00", Controller: &isController}
+	}
+	controlledByComposable := func() (bool, error) {
+		cm := &v1.ConfigMap{}
+		err := testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)
+		return metav1.IsControlledBy(cm, &comp), err
+	}
+
+	It("Composable with the Never adoption policy should refuse to take over an existing object", func() {
+		deploy(ibmcloudv1alpha1.AdoptionPolicyNever, nil)
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionApplied)).Should(HaveField("Reason", ibmcloudv1alpha1.ReasonAdoptionRefused))
+		Expect(test.GetStatusState(testContext, &comp)()).Should(Equal(FailedStatus))
+		Expect(controlledByComposable()).Should(BeFalse())
+	})
+
+	It("Composable with the IfUnowned adoption policy should adopt an object without controller", func() {
+		deploy(ibmcloudv1alpha1.AdoptionPolicyIfUnowned, nil)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+		Expect(controlledByComposable()).Should(BeTrue())
+	})
+
+	It("Composable with the IfUnowned adoption policy should refuse to take over an object with another controller", func() {
+		deploy(ibmcloudv1alpha1.AdoptionPolicyIfUnowned, controller())
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionApplied)).Should(HaveField("Reason", ibmcloudv1alpha1.ReasonAdoptionRefused))
+		Expect(controlledByComposable()).Should(BeFalse())
+	})
+
+	It("Composable with the Always adoption policy should take over an object with another controller", func() {
+		deploy(ibmcloudv1alpha1.AdoptionPolicyAlways, controller())
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+		Expect(controlledByComposable()).Should(BeTrue())
+	})
+})
+
+var _ = Describe("Composable drift policies", func() {
+	dataDir := "testdata/"
+	objNamespacednameOut := types.NamespacedName{Name: "comp-configmap-drift"}
+	var comp ibmcloudv1alpha1.Composable
+
+	BeforeEach(func() {
+		objNamespacednameOut.Namespace = testContext.Namespace()
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+	})
+
+	AfterEach(func() {
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+		test.DeleteObject(testContext, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}, false)
+	})
+
+	// deploy creates the Composable of the ConfigMap, and then changes a data field and adds a label to the ConfigMap
+	deploy := func(policy string) *v1.ConfigMap {
+		comp = test.LoadComposable(dataDir + "compConfigMap.yaml")
+		comp.Name = "to-configmap-drift"
+		comp.Spec.DriftPolicy = policy
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetName(objNamespacednameOut.Name)
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		cm := &v1.ConfigMap{}
+		Expect(testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)).Should(Succeed())
+		cm.Data["stringValue"] = "Changed by hand"
+		cm.Labels["owner"] = "someone"
+		test.UpdateObject(testContext, cm, false, 0)
+		return cm
+	}
+	stringValue := func() (string, error) {
+		cm := &v1.ConfigMap{}
+		err := testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)
+		return cm.Data["stringValue"], err
+	}
+
+	It("Composable with the Correct drift policy should correct and report the drifted fields", func() {
+		deploy(ibmcloudv1alpha1.DriftPolicyCorrect)
+		Eventually(stringValue).Should(Equal("Hello world"))
+		objects := test.GetStatusObjects(testContext, &comp)()
+		Expect(objects).Should(HaveLen(1))
+		Expect(objects[0].DriftedFields).Should(Equal([]string{"data.stringValue"}))
+		Expect(objects[0].LastDriftTime).ShouldNot(BeNil())
+	})
+
+	It("Composable with the Correct drift policy should take back the fields applied by another field manager", func() {
+		deploy(ibmcloudv1alpha1.DriftPolicyCorrect)
+		Eventually(stringValue).Should(Equal("Hello world"))
+
+		By("apply the ConfigMap with another field manager")
+		cm := &unstructured.Unstructured{}
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		cm.SetName(objNamespacednameOut.Name)
+		cm.SetNamespace(objNamespacednameOut.Namespace)
+		Expect(unstructured.SetNestedField(cm.Object, "Applied by another manager", "data", "stringValue")).Should(Succeed())
+		Expect(testContext.Client().Patch(context.TODO(), cm, client.Apply, client.FieldOwner("another-manager"), client.ForceOwnership)).Should(Succeed())
+
+		By("check that the field is corrected")
+		Eventually(stringValue).Should(Equal("Hello world"))
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionApplied)).Should(And(
+			HaveField("Status", metav1.ConditionTrue), HaveField("Reason", ibmcloudv1alpha1.ReasonApplied)))
+	})
+
+	It("Composable with the Report drift policy should only report the drifted fields", func() {
+		deploy(ibmcloudv1alpha1.DriftPolicyReport)
+		Eventually(func() []string {
+			objects := test.GetStatusObjects(testContext, &comp)()
+			if len(objects) == 0 {
+				return nil
+			}
+			return objects[0].DriftedFields
+		}).Should(Equal([]string{"data.stringValue"}))
+		Consistently(stringValue).Should(Equal("Changed by hand"))
+	})
+
+	It("Composable with the Ignore drift policy should neither correct nor report the drifted fields", func() {
+		deploy(ibmcloudv1alpha1.DriftPolicyIgnore)
+		Consistently(stringValue).Should(Equal("Changed by hand"))
+		Expect(test.GetStatusObjects(testContext, &comp)()).Should(ContainElement(HaveField("DriftedFields", BeEmpty())))
+	})
+})
+
+var _ = Describe("Suspended Composable objects", func() {
+	dataDir := "testdata/"
+	objNamespacednameOut := types.NamespacedName{Name: "comp-configmap-suspend"}
+	var comp ibmcloudv1alpha1.Composable
+
+	BeforeEach(func() {
+		objNamespacednameOut.Namespace = testContext.Namespace()
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+	})
+
+	AfterEach(func() {
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+		test.DeleteObject(testContext, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}, false)
+	})
+
+	stringValue := func() (string, error) {
+		cm := &v1.ConfigMap{}
+		err := testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)
+		return cm.Data["stringValue"], err
+	}
+
+	It("Composable should not revert changes of the underlying object while it is suspended", func() {
+		comp = test.LoadComposable(dataDir + "compConfigMap.yaml")
+		comp.Name = "to-configmap-suspend"
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetName(objNamespacednameOut.Name)
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		By("suspend the Composable with the annotation")
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(&comp), &comp)).Should(Succeed())
+		comp.SetAnnotations(map[string]string{ibmcloudv1alpha1.SuspendAnnotation: "true"})
+		test.UpdateObject(testContext, &comp, false, 0)
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionSuspended)).Should(HaveField("Status", metav1.ConditionTrue))
+
+		By("change the ConfigMap by hand")
+		cm := &v1.ConfigMap{}
+		Expect(testContext.Client().Get(context.TODO(), objNamespacednameOut, cm)).Should(Succeed())
+		cm.Data["stringValue"] = "Changed by hand"
+		test.UpdateObject(testContext, cm, false, 0)
+		Consistently(stringValue).Should(Equal("Changed by hand"))
+
+		By("resume the Composable")
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(&comp), &comp)).Should(Succeed())
+		comp.SetAnnotations(nil)
+		test.UpdateObject(testContext, &comp, false, 0)
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionSuspended)).Should(HaveField("Reason", ibmcloudv1alpha1.ReasonResumed))
+		Eventually(stringValue).Should(Equal("Hello world"))
+		Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionApplied)).Should(And(
+			HaveField("Status", metav1.ConditionTrue), HaveField("Reason", ibmcloudv1alpha1.ReasonApplied)))
+	})
+})
+
+var _ = Describe("Composable events", func() {
+	dataDir := "testdata/"
+	objNamespacednameOut := types.NamespacedName{Name: "comp-configmap-events"}
+	var comp ibmcloudv1alpha1.Composable
+
+	BeforeEach(func() {
+		objNamespacednameOut.Namespace = testContext.Namespace()
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+	})
+
+	AfterEach(func() {
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+		test.DeleteObject(testContext, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}, false)
+	})
+
+	eventReasons := func(name string) func() ([]string, error) {
+		return func() ([]string, error) {
+			events := &v1.EventList{}
+			if err := testContext.Client().List(context.TODO(), events, client.InNamespace(testContext.Namespace())); err != nil {
+				return nil, err
+			}
+			var reasons []string
+			for _, event := range events.Items {
+				if event.InvolvedObject.Name == name {
+					reasons = append(reasons, event.Reason)
+				}
+			}
+			return reasons, nil
+		}
+	}
+
+	It("Composable should record events of the created underlying object on both objects", func() {
+		comp = test.LoadComposable(dataDir + "compConfigMap.yaml")
+		comp.Name = "to-configmap-events"
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetName(objNamespacednameOut.Name)
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		Eventually(eventReasons(comp.Name)).Should(ContainElement(eventReasonCreated))
+		Eventually(eventReasons(objNamespacednameOut.Name)).Should(ContainElement(eventReasonCreated))
+	})
+})
+
+var _ = Describe("Composable metrics", func() {
+	dataDir := "testdata/"
+	objNamespacednameOut := types.NamespacedName{Name: "comp-configmap-metrics"}
+	var comp ibmcloudv1alpha1.Composable
+
+	BeforeEach(func() {
+		objNamespacednameOut.Namespace = testContext.Namespace()
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.CreateObject(testContext, obj, false, 0)
+		Eventually(test.GetObject(testContext, obj)).ShouldNot(BeNil())
+	})
+
+	AfterEach(func() {
+		test.DeleteInNs(testContext, &comp, false)
+		Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
+		obj := test.LoadObject(dataDir+"inputDataObject.yaml", &unstructured.Unstructured{})
+		test.DeleteObject(testContext, obj, false)
+		Eventually(test.GetObject(testContext, obj)).Should(BeNil())
+		test.DeleteObject(testContext, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: objNamespacednameOut.Name, Namespace: objNamespacednameOut.Namespace}}, false)
+	})
+
+	It("Composable should report the resolution and the application of its templates", func() {
+		resolvedBefore := testutil.ToFloat64(referencesResolved)
+		appliedBefore := testutil.ToFloat64(applyOutcomes.WithLabelValues(ibmcloudv1alpha1.ReasonApplied))
+
+		comp = test.LoadComposable(dataDir + "compConfigMap.yaml")
+		comp.Name = "to-configmap-metrics"
+		template := unstructured.Unstructured{}
+		Expect(template.UnmarshalJSON(comp.Spec.Template.Raw)).Should(Succeed())
+		template.SetName(objNamespacednameOut.Name)
+		comp.Spec.Template.Raw, _ = template.MarshalJSON()
+		test.PostInNs(testContext, &comp, false, 0)
+		Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(OnlineStatus))
+
+		Expect(testutil.ToFloat64(referencesResolved)).Should(BeNumerically(">", resolvedBefore))
+		Expect(testutil.ToFloat64(applyOutcomes.WithLabelValues(ibmcloudv1alpha1.ReasonApplied))).Should(BeNumerically(">", appliedBefore))
+		Expect(testutil.ToFloat64(composablesByState.WithLabelValues(OnlineStatus))).Should(BeNumerically(">=", 1))
+		Expect(testutil.ToFloat64(dynamicWatches.WithLabelValues(watchUnderlying))).Should(BeNumerically(">=", 1))
+		Expect(testutil.CollectAndCount(resolutionDuration)).Should(BeNumerically(">=", 1))
+
+		By("reconcile the Composable without applying the ConfigMap")
+		appliedBefore = testutil.ToFloat64(applyOutcomes.WithLabelValues(ibmcloudv1alpha1.ReasonApplied))
+		Expect(testContext.Client().Get(context.TODO(), client.ObjectKeyFromObject(&comp), &comp)).Should(Succeed())
+		comp.SetAnnotations(map[string]string{"test": "reconcile"})
+		test.UpdateObject(testContext, &comp, false, 0)
+		Consistently(func() float64 {
+			return testutil.ToFloat64(applyOutcomes.WithLabelValues(ibmcloudv1alpha1.ReasonApplied))
+		}).Should(Equal(appliedBefore))
+	})
+})
+
+var _ = Describe("Rate limited event recorder", func() {
+	var fake *record.FakeRecorder
+	var recorder *rateLimitedRecorder
+	var now time.Time
+
+	BeforeEach(func() {
+		fake = record.NewFakeRecorder(10)
+		recorder = newRateLimitedRecorder(fake, time.Minute)
+		now = time.Now()
+		recorder.now = func() time.Time { return now }
+	})
+
+	It("should drop identical events of an object within the interval", func() {
+		comp := &ibmcloudv1alpha1.Composable{ObjectMeta: metav1.ObjectMeta{Name: "comp", UID: "1"}}
+		recorder.Eventf(comp, v1.EventTypeWarning, eventReasonResolveFailed, "Reference to %s", "a")
+		recorder.Eventf(comp, v1.EventTypeWarning, eventReasonResolveFailed, "Reference to %s", "a")
+		Expect(fake.Events).Should(HaveLen(1))
+
+		By("recording other events and events of other objects")
+		recorder.Eventf(comp, v1.EventTypeWarning, eventReasonResolveFailed, "Reference to %s", "b")
+		other := &ibmcloudv1alpha1.Composable{ObjectMeta: metav1.ObjectMeta{Name: "other", UID: "2"}}
+		recorder.Eventf(other, v1.EventTypeWarning, eventReasonResolveFailed, "Reference to %s", "a")
+		Expect(fake.Events).Should(HaveLen(3))
+
+		By("recording the event again after the interval")
+		now = now.Add(time.Minute)
+		recorder.Eventf(comp, v1.EventTypeWarning, eventReasonResolveFailed, "Reference to %s", "a")
+		Expect(fake.Events).Should(HaveLen(4))
+		Expect(recorder.recorded).Should(HaveLen(1))
+	})
+})
+
 var _ = Describe("Validate input objects Api grop and version discovery", func() {
 	Context("There are 3 groups that have Kind = `Service`. They are: Service/v1; Service.ibmcloud.ibm.com/v1alpha1 and Service.test.ibmcloud.ibm.com/v1", func() {
 		dataDir := "testdata/"
@@ -312,6 +944,10 @@ var _ = Describe("Validate input objects Api grop and version discovery", func()
 
 			By("validate that Composable object status is FailedStatus")
 			Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(FailedStatus))
+
+			By("validate the Composable object conditions")
+			Eventually(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionReady)).Should(HaveField("Reason", ibmcloudv1alpha1.ReasonKindNotFound))
+			Expect(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionDegraded)()).Should(HaveField("Status", metav1.ConditionTrue))
 		})
 		It("Composable should fail to discover correct Service recourse, when a wrong API version is provided", func() {
 			By("deploy Composable object " + "compAPIWrongVersionError.yaml")
@@ -363,6 +999,7 @@ var _ = Describe("Find input objects according their labels", func() {
 			Eventually(test.GetObject(testContext, &comp)).ShouldNot(BeNil())
 			Eventually(test.GetStatusState(testContext, &comp)).Should(Equal(FailedStatus))
 			Expect(test.GetStatusMessage(testContext, &comp)()).Should(ContainSubstring("neither 'name' nor 'labels' are defined (one expected)"))
+			Expect(test.GetStatusCondition(testContext, &comp, ibmcloudv1alpha1.ConditionResolved)()).Should(HaveField("Reason", ibmcloudv1alpha1.ReasonIllFormedRef))
 			test.DeleteInNs(testContext, &comp, false)
 			Eventually(test.GetObject(testContext, &comp)).Should(BeNil())
 		})
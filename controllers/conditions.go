@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
+	sdk "github.com/composable-operator/composable/sdk"
+)
+
+// setCondition sets the condition of the given type in the status
+func setCondition(status *ibmcloudv1alpha1.ComposableStatus, generation int64, conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// setFailed sets the Failed state, and the given condition to False with the reason of the failure
+func setFailed(status *ibmcloudv1alpha1.ComposableStatus, generation int64, conditionType, reason string, err error) {
+	status.State = FailedStatus
+	status.Message = err.Error()
+	setCondition(status, generation, conditionType, metav1.ConditionFalse, reason, err.Error())
+}
+
+// setPending sets the Pending state, and the given condition to False with the reason of the wait
+func setPending(status *ibmcloudv1alpha1.ComposableStatus, generation int64, conditionType, reason string, err error) {
+	status.State = PendingStatus
+	status.Message = err.Error()
+	setCondition(status, generation, conditionType, metav1.ConditionFalse, reason, err.Error())
+}
+
+// resolveErrorReason returns the condition reason of an error returned by the resolver
+func resolveErrorReason(err error) string {
+	var resolveErr *sdk.ResolveError
+	if !errors.As(err, &resolveErr) {
+		return ibmcloudv1alpha1.ReasonResolveError
+	}
+	switch resolveErr.Reason {
+	case sdk.ReasonIllFormedRef:
+		return ibmcloudv1alpha1.ReasonIllFormedRef
+	case sdk.ReasonKindNotFound:
+		return ibmcloudv1alpha1.ReasonKindNotFound
+	case sdk.ReasonObjectNotFound:
+		return ibmcloudv1alpha1.ReasonObjectNotFound
+	case sdk.ReasonValueNotFound:
+		return ibmcloudv1alpha1.ReasonValueNotFound
+	case sdk.ReasonNotReady:
+		return ibmcloudv1alpha1.ReasonInputNotReady
+	default:
+		return ibmcloudv1alpha1.ReasonResolveError
+	}
+}
+
+// setSummaryConditions sets the Ready and Degraded conditions according to the state and the other conditions
+func setSummaryConditions(status *ibmcloudv1alpha1.ComposableStatus, generation int64) {
+	reason, message := ibmcloudv1alpha1.ReasonPending, status.Message
+	for _, conditionType := range []string{ibmcloudv1alpha1.ConditionResolved, ibmcloudv1alpha1.ConditionApplied} {
+		if cond := meta.FindStatusCondition(status.Conditions, conditionType); cond != nil && cond.Status == metav1.ConditionFalse {
+			reason, message = cond.Reason, cond.Message
+			break
+		}
+	}
+
+	switch status.State {
+	case OnlineStatus:
+		setCondition(status, generation, ibmcloudv1alpha1.ConditionReady, metav1.ConditionTrue, ibmcloudv1alpha1.ReasonApplied, "The underlying object is up-to-date")
+		setCondition(status, generation, ibmcloudv1alpha1.ConditionDegraded, metav1.ConditionFalse, ibmcloudv1alpha1.ReasonApplied, "")
+	case FailedStatus:
+		setCondition(status, generation, ibmcloudv1alpha1.ConditionReady, metav1.ConditionFalse, reason, message)
+		setCondition(status, generation, ibmcloudv1alpha1.ConditionDegraded, metav1.ConditionTrue, reason, message)
+	default:
+		setCondition(status, generation, ibmcloudv1alpha1.ConditionReady, metav1.ConditionFalse, reason, message)
+		setCondition(status, generation, ibmcloudv1alpha1.ConditionDegraded, metav1.ConditionFalse, reason, "")
+	}
+}
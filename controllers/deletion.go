@@ -0,0 +1,157 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
+)
+
+// composableFinalizer - the finalizer of Composables whose deletion policy is not Delete
+const composableFinalizer = "ibmcloud.ibm.com/composable"
+
+// deletionPolicy returns the deletion policy of the Composable, Delete if it is not defined
+func deletionPolicy(compInstance *ibmcloudv1alpha1.Composable) string {
+	if len(compInstance.Spec.DeletionPolicy) == 0 {
+		return ibmcloudv1alpha1.DeletionPolicyDelete
+	}
+	return compInstance.Spec.DeletionPolicy
+}
+
+// updateFinalizer adds the finalizer to a Composable whose underlying objects should not be garbage collected with it,
+// and removes the finalizer if the deletion policy has been changed to Delete
+func (r *ComposableReconciler) updateFinalizer(ctx context.Context, compInstance *ibmcloudv1alpha1.Composable) error {
+	needed := deletionPolicy(compInstance) != ibmcloudv1alpha1.DeletionPolicyDelete
+	if needed == controllerutil.ContainsFinalizer(compInstance, composableFinalizer) {
+		return nil
+	}
+	patch := client.MergeFrom(compInstance.DeepCopy())
+	if needed {
+		controllerutil.AddFinalizer(compInstance, composableFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(compInstance, composableFinalizer)
+	}
+	log.FromContext(ctx).Info("Update finalizer", "deletionPolicy", deletionPolicy(compInstance), "finalizers", compInstance.Finalizers)
+	return r.Patch(ctx, compInstance, patch)
+}
+
+// finalize applies the deletion policy of a deleted Composable, and then removes its finalizer, so the deletion proceeds.
+// A retained Composable is left pending, until the AllowDeletionAnnotation is set.
+func (r *ComposableReconciler) finalize(ctx context.Context, compInstance *ibmcloudv1alpha1.Composable, status *ibmcloudv1alpha1.ComposableStatus) error {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(compInstance, composableFinalizer) {
+		return nil
+	}
+	switch deletionPolicy(compInstance) {
+	case ibmcloudv1alpha1.DeletionPolicyRetain:
+		if compInstance.Annotations[ibmcloudv1alpha1.AllowDeletionAnnotation] != "true" {
+			logger.Info("Deletion is retained", "annotation", ibmcloudv1alpha1.AllowDeletionAnnotation)
+			r.event(compInstance, corev1.EventTypeNormal, eventReasonDeletionRetained, "The deletion is retained until the %s annotation is \"true\"", ibmcloudv1alpha1.AllowDeletionAnnotation)
+			status.State = PendingStatus
+			status.Message = fmt.Sprintf("The deletion is retained until the %s annotation is \"true\"", ibmcloudv1alpha1.AllowDeletionAnnotation)
+			status.Objects = compInstance.Status.Objects
+			status.Inputs = compInstance.Status.Inputs
+			return nil
+		}
+	case ibmcloudv1alpha1.DeletionPolicyOrphan:
+		for _, object := range underlyingObjects(compInstance) {
+			if err := r.orphanUnderlyingObject(ctx, compInstance, object); err != nil {
+				logger.Error(err, "Cannot orphan underlying object", "kind", object.Kind, "name", object.Name)
+				return err
+			}
+		}
+	}
+	logger.Info("Remove finalizer", "deletionPolicy", deletionPolicy(compInstance))
+	r.event(compInstance, corev1.EventTypeNormal, eventReasonDeleting, "The Composable is deleted with the %s deletion policy", deletionPolicy(compInstance))
+	patch := client.MergeFrom(compInstance.DeepCopy())
+	controllerutil.RemoveFinalizer(compInstance, composableFinalizer)
+	return r.Patch(ctx, compInstance, patch)
+}
+
+// underlyingObjects returns the underlying objects reported in the status of the Composable, and the objects of the
+// templates that define their names literally, in case the status has not been written, e.g. when the first
+// reconciliation failed after the objects were created
+func underlyingObjects(compInstance *ibmcloudv1alpha1.Composable) []ibmcloudv1alpha1.UnderlyingObjectStatus {
+	objects := append([]ibmcloudv1alpha1.UnderlyingObjectStatus(nil), compInstance.Status.Objects...)
+	for _, template := range compInstance.Spec.AllTemplates() {
+		obj := unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(template.Raw); err != nil {
+			continue
+		}
+		// the names and kinds that are set by object references are unknown until the templates are resolved
+		object := ibmcloudv1alpha1.UnderlyingObjectStatus{APIVersion: obj.GetAPIVersion(), Kind: obj.GetKind(), Name: obj.GetName()}
+		if len(object.APIVersion) == 0 || len(object.Kind) == 0 || len(object.Name) == 0 ||
+			strings.Contains(object.APIVersion+object.Kind+object.Name, "${") {
+			continue
+		}
+		found := false
+		for _, reported := range objects {
+			if reported.APIVersion == object.APIVersion && reported.Kind == object.Kind && reported.Name == object.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// orphanUnderlyingObject removes the owner reference to the Composable from the underlying object,
+// so the garbage collector does not delete it
+func (r *ComposableReconciler) orphanUnderlyingObject(ctx context.Context, compInstance *ibmcloudv1alpha1.Composable, object ibmcloudv1alpha1.UnderlyingObjectStatus) error {
+	underlyingObj := &unstructured.Unstructured{}
+	underlyingObj.SetAPIVersion(object.APIVersion)
+	underlyingObj.SetKind(object.Kind)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: compInstance.Namespace, Name: object.Name}, underlyingObj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	owners := underlyingObj.GetOwnerReferences()
+	kept := make([]metav1.OwnerReference, 0, len(owners))
+	for _, owner := range owners {
+		if owner.UID != compInstance.UID {
+			kept = append(kept, owner)
+		}
+	}
+	if len(kept) == len(owners) {
+		return nil
+	}
+	patch := client.MergeFrom(underlyingObj.DeepCopy())
+	underlyingObj.SetOwnerReferences(kept)
+	log.FromContext(ctx).Info("Orphan underlying object", "kind", object.Kind, "name", object.Name)
+	if err := r.Patch(ctx, underlyingObj, patch); err != nil {
+		return err
+	}
+	r.underlyingEvent(compInstance, underlyingObj, corev1.EventTypeNormal, eventReasonOrphaned, "The object is orphaned, it is not deleted with the Composable")
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
+	sdk "github.com/composable-operator/composable/sdk"
+)
+
+const (
+	// eventReasonDriftDetected - the reason of the events of reported drifts
+	eventReasonDriftDetected = "DriftDetected"
+	// eventReasonDriftCorrected - the reason of the events of corrected drifts
+	eventReasonDriftCorrected = "DriftCorrected"
+)
+
+// driftPolicy returns the drift policy of the Composable, Correct if it is not defined
+func driftPolicy(compInstance *ibmcloudv1alpha1.Composable) string {
+	if len(compInstance.Spec.DriftPolicy) == 0 {
+		return ibmcloudv1alpha1.DriftPolicyCorrect
+	}
+	return compInstance.Spec.DriftPolicy
+}
+
+// templateHash returns the hash of the applied template, it identifies the template that the underlying object was updated with
+func templateHash(template *unstructured.Unstructured) string {
+	data, err := json.Marshal(template.Object)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// previousObjectStatus returns the status of the underlying object reported by the last reconciliation, or nil
+func previousObjectStatus(compInstance *ibmcloudv1alpha1.Composable, template *unstructured.Unstructured) *ibmcloudv1alpha1.UnderlyingObjectStatus {
+	for i, object := range compInstance.Status.Objects {
+		if object.APIVersion == template.GetAPIVersion() && object.Kind == template.GetKind() && object.Name == template.GetName() {
+			return &compInstance.Status.Objects[i]
+		}
+	}
+	return nil
+}
+
+// driftedFields returns the paths of the template fields whose values differ in the underlying object, sorted.
+// Only the fields defined in the template, i.e. the fields that the Composable manages, are compared, so fields that
+// are set by the API server defaults or by other controllers are not reported. Labels and annotations are compared
+// as subsets, and the status and server-managed metadata fields of the template are ignored.
+func driftedFields(template, underlyingObj *unstructured.Unstructured) []string {
+	var drifted []string
+	for key, value := range template.Object {
+		if key == status {
+			continue
+		}
+		if key != sdk.Metadata {
+			drifted = append(drifted, diffValues(value, underlyingObj.Object[key], field.NewPath(key))...)
+			continue
+		}
+		metaPath := field.NewPath(sdk.Metadata)
+		drifted = append(drifted, diffValues(toInterfaceMap(template.GetLabels()), toInterfaceMap(underlyingObj.GetLabels()), metaPath.Child("labels"))...)
+		drifted = append(drifted, diffValues(toInterfaceMap(template.GetAnnotations()), toInterfaceMap(underlyingObj.GetAnnotations()), metaPath.Child("annotations"))...)
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+// diffValues returns the paths of the values of the template that differ from the actual values.
+// Keys that are not in the template are ignored, and lists are compared element by element.
+func diffValues(template, actual interface{}, fldPath *field.Path) []string {
+	switch t := template.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return []string{fldPath.String()}
+		}
+		var drifted []string
+		for key, value := range t {
+			drifted = append(drifted, diffValues(value, a[key], fldPath.Child(key))...)
+		}
+		return drifted
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(t) {
+			return []string{fldPath.String()}
+		}
+		var drifted []string
+		for i := range t {
+			drifted = append(drifted, diffValues(t[i], a[i], fldPath.Index(i))...)
+		}
+		return drifted
+	default:
+		if !jsonEqual(template, actual) && !quantitiesEqual(template, actual) {
+			return []string{fldPath.String()}
+		}
+		return nil
+	}
+}
+
+// quantitiesEqual returns true if both values are equal resource quantities, e.g. "1" and "1000m", which the API server
+// might normalize
+func quantitiesEqual(template, actual interface{}) bool {
+	t, ok := template.(string)
+	if !ok {
+		return false
+	}
+	a, ok := actual.(string)
+	if !ok {
+		return false
+	}
+	tq, err := resource.ParseQuantity(t)
+	if err != nil {
+		return false
+	}
+	aq, err := resource.ParseQuantity(a)
+	return err == nil && tq.Cmp(aq) == 0
+}
+
+// toInterfaceMap converts labels or annotations to the values of an unstructured object
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		out[key] = value
+	}
+	return out
+}
+
+// setDrift reports the drifted fields in the status of the underlying object, and returns true if the drift is new.
+// A drift of the same fields is detected by every reconciliation, both when it is only reported and when its
+// correction does not stick, e.g. another controller keeps changing the fields, so its fields and detection time are kept.
+func setDrift(objStatus *ibmcloudv1alpha1.UnderlyingObjectStatus, drifted []string) bool {
+	if objStatus.LastDriftTime != nil && reflect.DeepEqual(objStatus.DriftedFields, drifted) {
+		return false
+	}
+	now := metav1.Now()
+	objStatus.DriftedFields = drifted
+	objStatus.LastDriftTime = &now
+	return true
+}
+
+// recordDrift records an event of the drift on the Composable and on the underlying object
+func (r *ComposableReconciler) recordDrift(compInstance *ibmcloudv1alpha1.Composable, underlyingObj *unstructured.Unstructured, drifted []string, corrected bool) {
+	if r.Recorder == nil {
+		return
+	}
+	reason, action := eventReasonDriftDetected, "reported"
+	if corrected {
+		reason, action = eventReasonDriftCorrected, "corrected"
+	}
+	fields := strings.Join(drifted, ", ")
+	r.Recorder.Eventf(compInstance, corev1.EventTypeWarning, reason, "Drift of %s %s is %s, fields: %s", underlyingObj.GetKind(), underlyingObj.GetName(), action, fields)
+	r.Recorder.Eventf(underlyingObj, corev1.EventTypeWarning, reason, "Drift from Composable %s is %s, fields: %s", compInstance.Name, action, fields)
+}
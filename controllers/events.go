@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
+	sdk "github.com/composable-operator/composable/sdk"
+)
+
+const (
+	// the reasons of the events of Composables and their underlying objects
+	eventReasonResolveFailed    = "ResolveFailed"
+	eventReasonInputNotReady    = "InputNotReady"
+	eventReasonCreated          = "Created"
+	eventReasonUpdated          = "Updated"
+	eventReasonApplyFailed      = "ApplyFailed"
+	eventReasonAdoptionRefused  = "AdoptionRefused"
+	eventReasonWatchRegistered  = "WatchRegistered"
+	eventReasonDeleting         = "Deleting"
+	eventReasonDeletionRetained = "DeletionRetained"
+	eventReasonOrphaned         = "Orphaned"
+
+	// defaultEventsInterval - the default minimal interval between identical events of an object
+	defaultEventsInterval = 5 * time.Minute
+)
+
+// rateLimitedRecorder is an EventRecorder that drops the events that are identical to an event recorded for the same
+// object within the interval, so a hot-looping Composable does not flood the API server with events
+type rateLimitedRecorder struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	// now returns the current time, it can be replaced by tests
+	now func() time.Time
+
+	lock sync.Mutex
+	// recorded holds the times when the events were recorded last time
+	recorded map[string]time.Time
+	// lastPurge is the time when the expired entries of recorded were removed last time
+	lastPurge time.Time
+}
+
+var _ record.EventRecorder = &rateLimitedRecorder{}
+
+// newRateLimitedRecorder returns an EventRecorder that records an event of an object at most once in the interval
+func newRateLimitedRecorder(recorder record.EventRecorder, interval time.Duration) *rateLimitedRecorder {
+	if interval <= 0 {
+		interval = defaultEventsInterval
+	}
+	return &rateLimitedRecorder{
+		recorder: recorder,
+		interval: interval,
+		now:      time.Now,
+		recorded: make(map[string]time.Time),
+	}
+}
+
+// Event records the event, unless it was recorded within the interval
+func (r *rateLimitedRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+// Eventf records the event with a formatted message, unless it was recorded within the interval
+func (r *rateLimitedRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// AnnotatedEventf records the annotated event with a formatted message, unless it was recorded within the interval
+func (r *rateLimitedRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, "%s", message)
+	}
+}
+
+// allow returns true if the event has not been recorded for the object within the interval, and remembers it
+func (r *rateLimitedRecorder) allow(object runtime.Object, eventtype, reason, message string) bool {
+	key := fmt.Sprintf("%s/%s/%s/%s", objectKey(object), eventtype, reason, message)
+	now := r.now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if now.Sub(r.lastPurge) >= r.interval {
+		for k, recorded := range r.recorded {
+			if now.Sub(recorded) >= r.interval {
+				delete(r.recorded, k)
+			}
+		}
+		r.lastPurge = now
+	}
+	if recorded, ok := r.recorded[key]; ok && now.Sub(recorded) < r.interval {
+		return false
+	}
+	r.recorded[key] = now
+	return true
+}
+
+// objectKey identifies the object of an event by its UID, or by its kind, namespace and name if it has no UID
+func objectKey(object runtime.Object) string {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return fmt.Sprintf("%p", object)
+	}
+	if uid := accessor.GetUID(); len(uid) > 0 {
+		return string(uid)
+	}
+	return fmt.Sprintf("%s/%s/%s", object.GetObjectKind().GroupVersionKind().String(), accessor.GetNamespace(), accessor.GetName())
+}
+
+// event records an event of the Composable, if the reconciler has a Recorder
+func (r *ComposableReconciler) event(compInstance *ibmcloudv1alpha1.Composable, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(compInstance, eventtype, reason, messageFmt, args...)
+}
+
+// underlyingEvent records an event on the Composable and on its underlying object, if the reconciler has a Recorder.
+// The event of the underlying object is not recorded if the object does not exist.
+func (r *ComposableReconciler) underlyingEvent(compInstance *ibmcloudv1alpha1.Composable, underlyingObj *unstructured.Unstructured, eventtype, reason, message string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(compInstance, eventtype, reason, "%s %s: %s", underlyingObj.GetKind(), underlyingObj.GetName(), message)
+	if len(underlyingObj.GetUID()) > 0 {
+		r.Recorder.Eventf(underlyingObj, eventtype, reason, "Composable %s: %s", compInstance.Name, message)
+	}
+}
+
+// resolveFailedEvent records the event of a failed resolution of the Composable, with the failing object reference
+func (r *ComposableReconciler) resolveFailedEvent(compInstance *ibmcloudv1alpha1.Composable, err error) {
+	eventtype, reason := corev1.EventTypeWarning, eventReasonResolveFailed
+	if sdk.IsNotReady(err) {
+		eventtype, reason = corev1.EventTypeNormal, eventReasonInputNotReady
+	}
+	var rerr *sdk.ResolveError
+	if !errors.As(err, &rerr) || len(rerr.Name) == 0 {
+		r.event(compInstance, eventtype, reason, "%s", err.Error())
+		return
+	}
+	ref := fmt.Sprintf("%s %s/%s", rerr.GroupVersionKind.Kind, rerr.Namespace, rerr.Name)
+	if len(rerr.Path) > 0 {
+		ref = fmt.Sprintf("%s, path %s", ref, rerr.Path)
+	}
+	r.event(compInstance, eventtype, reason, "Reference to %s: %s", ref, err.Error())
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ibmcloudv1alpha1 "github.com/composable-operator/composable/api/v1alpha1"
+	sdk "github.com/composable-operator/composable/sdk"
+)
+
+const (
+	// inputsIndexKey - name of the field index over the input objects of Composables
+	inputsIndexKey = ".status.inputs"
+
+	// labelsSelected - replaces the object name in the index values of input objects selected by labels or fields
+	labelsSelected = "*"
+)
+
+// inputIndexValue returns the index value of an input object
+func inputIndexValue(gvk schema.GroupVersionKind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", gvk.GroupKind().String(), namespace, name)
+}
+
+// indexInputs is the field indexer function, it returns index values for all input objects of a Composable
+func indexInputs(obj client.Object) []string {
+	comp, ok := obj.(*ibmcloudv1alpha1.Composable)
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(comp.Status.Inputs))
+	for _, input := range comp.Status.Inputs {
+		gvk := schema.FromAPIVersionAndKind(input.APIVersion, input.Kind)
+		name := input.Name
+		if len(name) == 0 {
+			name = labelsSelected
+		}
+		values = append(values, inputIndexValue(gvk, input.Namespace, name))
+	}
+	return values
+}
+
+// toInputReferences converts input objects returned by the resolver to their status representation
+func toInputReferences(inputs []sdk.InputObject) []ibmcloudv1alpha1.InputObjectReference {
+	if len(inputs) == 0 {
+		return nil
+	}
+	refs := make([]ibmcloudv1alpha1.InputObjectReference, 0, len(inputs))
+	for _, input := range inputs {
+		apiVersion, kind := input.GroupVersionKind.ToAPIVersionAndKind()
+		refs = append(refs, ibmcloudv1alpha1.InputObjectReference{
+			APIVersion:    apiVersion,
+			Kind:          kind,
+			Namespace:     input.Namespace,
+			Name:          input.Name,
+			Labels:        input.Labels,
+			LabelSelector: input.LabelSelector,
+			FieldSelector: input.FieldSelector,
+		})
+	}
+	return refs
+}
+
+// resolveObject resolves the object with the named references of the Composable, and returns its input objects
+// if the resolver reports them
+func (r *ComposableReconciler) resolveObject(ctx context.Context, object interface{}, refs map[string]interface{}, resolved interface{}) ([]sdk.InputObject, error) {
+	if resolver, ok := r.Resolver.(sdk.OptionsResolver); ok {
+		stats := sdk.ResolveStats{}
+		inputs, err := resolver.ResolveObjectWithOptions(ctx, object, resolved, sdk.ResolveOptions{Refs: refs, RefsPath: field.NewPath(spec, refsKey), Stats: &stats})
+		recordResolveStats(stats)
+		return inputs, err
+	}
+	if len(refs) > 0 {
+		return nil, fmt.Errorf("the resolver does not support named references")
+	}
+	if resolver, ok := r.Resolver.(sdk.DependencyResolver); ok {
+		return resolver.ResolveObjectDependencies(ctx, object, resolved)
+	}
+	return nil, r.Resolver.ResolveObject(ctx, object, resolved)
+}
+
+// watchInputs registers a watch for every input object kind that is not watched yet
+func (r *ComposableReconciler) watchInputs(ctx context.Context, compInstance *ibmcloudv1alpha1.Composable, inputs []sdk.InputObject) error {
+	logger := log.FromContext(ctx)
+
+	r.watchesLock.Lock()
+	defer r.watchesLock.Unlock()
+	if r.inputWatches == nil {
+		r.inputWatches = make(map[schema.GroupVersionKind]bool)
+	}
+	for _, input := range inputs {
+		gvk := input.GroupVersionKind
+		if r.inputWatches[gvk] {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		logger.Info("Add input objects watcher", "groupVersionKind", gvk)
+		if err := r.Controller.Watch(&source.Kind{Type: obj}, handler.EnqueueRequestsFromMapFunc(r.inputToComposables(gvk))); err != nil {
+			logger.Error(err, "Cannot add input objects watcher", "groupVersionKind", gvk)
+			return err
+		}
+		r.inputWatches[gvk] = true
+		dynamicWatches.WithLabelValues(watchInputs).Set(float64(len(r.inputWatches)))
+		r.event(compInstance, corev1.EventTypeNormal, eventReasonWatchRegistered, "Watching input objects of kind %s", gvk.String())
+	}
+	return nil
+}
+
+// inputToComposables returns a function that maps an input object of the given kind to the Composables that depend on it
+func (r *ComposableReconciler) inputToComposables(gvk schema.GroupVersionKind) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+
+		byName := &ibmcloudv1alpha1.ComposableList{}
+		err := r.List(context.TODO(), byName, client.MatchingFields{inputsIndexKey: inputIndexValue(gvk, obj.GetNamespace(), obj.GetName())})
+		if err != nil {
+			log.Log.Error(err, "Cannot list Composables by input object", "groupVersionKind", gvk, "object", client.ObjectKeyFromObject(obj))
+			return nil
+		}
+		for _, comp := range byName.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: comp.Namespace, Name: comp.Name}})
+		}
+
+		byLabels := &ibmcloudv1alpha1.ComposableList{}
+		err = r.List(context.TODO(), byLabels, client.MatchingFields{inputsIndexKey: inputIndexValue(gvk, obj.GetNamespace(), labelsSelected)})
+		if err != nil {
+			log.Log.Error(err, "Cannot list Composables by input object labels", "groupVersionKind", gvk, "object", client.ObjectKeyFromObject(obj))
+			return requests
+		}
+		for _, comp := range byLabels.Items {
+			for _, input := range comp.Status.Inputs {
+				inputGK := schema.FromAPIVersionAndKind(input.APIVersion, input.Kind).GroupKind()
+				if len(input.Name) == 0 && inputGK == gvk.GroupKind() && input.Namespace == obj.GetNamespace() && selects(input, obj) {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: comp.Namespace, Name: comp.Name}})
+					break
+				}
+			}
+		}
+		return requests
+	}
+}
+
+// selects returns true if the input object reference selects the object by its labels or fields
+func selects(input ibmcloudv1alpha1.InputObjectReference, obj client.Object) bool {
+	if len(input.LabelSelector) == 0 && len(input.FieldSelector) == 0 {
+		return labels.SelectorFromSet(input.Labels).Matches(labels.Set(obj.GetLabels()))
+	}
+	unstrObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			log.Log.Error(err, "Cannot convert input object", "object", client.ObjectKeyFromObject(obj))
+			return false
+		}
+		unstrObj = &unstructured.Unstructured{Object: content}
+	}
+	labelSelector := input.LabelSelector
+	if len(input.Labels) > 0 {
+		labelSelector = labels.SelectorFromSet(input.Labels).String()
+	}
+	matches, err := sdk.SelectorMatches(unstrObj, labelSelector, input.FieldSelector)
+	if err != nil {
+		log.Log.Error(err, "Cannot match input object", "object", client.ObjectKeyFromObject(obj))
+		return false
+	}
+	return matches
+}
+
+// crdToComposables drops the cached API resources when a CRD is changed, and maps the CRD to the Composables
+// that failed to resolve the kind of an input object, so they are reconciled with the new kind
+func (r *ComposableReconciler) crdToComposables(obj client.Object) []reconcile.Request {
+	if invalidator, ok := r.Resolver.(sdk.DiscoveryInvalidator); ok {
+		invalidator.InvalidateDiscovery()
+	}
+
+	comps := &ibmcloudv1alpha1.ComposableList{}
+	if err := r.List(context.TODO(), comps); err != nil {
+		log.Log.Error(err, "Cannot list Composables", "customResourceDefinition", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, comp := range comps.Items {
+		cond := meta.FindStatusCondition(comp.Status.Conditions, ibmcloudv1alpha1.ConditionResolved)
+		if cond != nil && cond.Status == metav1.ConditionFalse && cond.Reason == ibmcloudv1alpha1.ReasonKindNotFound {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: comp.Namespace, Name: comp.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	sdk "github.com/composable-operator/composable/sdk"
+)
+
+const (
+	// metricsNamespace - the prefix of the names of the Composable controller metrics
+	metricsNamespace = "composable"
+
+	// the kinds of the dynamically registered watches
+	watchInputs     = "input"
+	watchUnderlying = "underlying"
+
+	// the results of the input object lookups
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+var (
+	// resolutionDuration - the latency of the resolution of the templates of a Composable
+	resolutionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "resolution_duration_seconds",
+		Help:      "Duration of the resolution of the templates of a Composable, in seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"namespace", "name"})
+
+	// referencesResolved - the number of resolved getValueFrom references
+	referencesResolved = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "references_resolved_total",
+		Help:      "Total number of resolved getValueFrom references",
+	})
+
+	// discoveryCalls - the number of calls of the discovery API, that are not served by the discovery cache
+	discoveryCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "discovery_calls_total",
+		Help:      "Total number of calls of the discovery API, by method",
+	}, []string{"method"})
+
+	// inputCacheLookups - the number of input object lookups, by whether they were served by the resolution cache
+	inputCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "input_cache_lookups_total",
+		Help:      "Total number of input object lookups, by result (hit or miss of the resolution cache)",
+	}, []string{"result"})
+
+	// applyOutcomes - the number of applications of underlying objects that are sent to the API server, by the reason of
+	// the Applied condition. Reconciliations that do not apply the underlying objects are not counted.
+	applyOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "apply_total",
+		Help:      "Total number of underlying object applications, by the reason of their outcome",
+	}, []string{"reason"})
+
+	// composablesByState - the number of Composables in every state
+	composablesByState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "composables",
+		Help:      "Number of Composables, by state",
+	}, []string{"state"})
+
+	// dynamicWatches - the number of watches registered for the kinds of input and underlying objects
+	dynamicWatches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "dynamic_watches",
+		Help:      "Number of dynamically registered watches, by the kind of the watched objects (input or underlying)",
+	}, []string{"kind"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(resolutionDuration, referencesResolved, discoveryCalls, inputCacheLookups,
+		applyOutcomes, composablesByState, dynamicWatches)
+}
+
+// recordResolveStats adds the statistics of a resolution to the metrics
+func recordResolveStats(stats sdk.ResolveStats) {
+	referencesResolved.Add(float64(stats.References))
+	inputCacheLookups.WithLabelValues(cacheHit).Add(float64(stats.CacheHits))
+	inputCacheLookups.WithLabelValues(cacheMiss).Add(float64(stats.CacheMisses))
+}
+
+// stateTracker holds the last reported state of every Composable, and counts the Composables per state
+type stateTracker struct {
+	lock   sync.Mutex
+	states map[types.NamespacedName]string
+}
+
+// set records the state of a Composable, an empty state removes the Composable, e.g. when it is deleted
+func (t *stateTracker) set(name types.NamespacedName, state string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.states == nil {
+		t.states = make(map[types.NamespacedName]string)
+	}
+	previous, ok := t.states[name]
+	if ok && previous == state {
+		return
+	}
+	if ok {
+		composablesByState.WithLabelValues(previous).Dec()
+	}
+	if len(state) == 0 {
+		delete(t.states, name)
+		resolutionDuration.DeleteLabelValues(name.Namespace, name.Name)
+		return
+	}
+	t.states[name] = state
+	composablesByState.WithLabelValues(state).Inc()
+}
+
+// countingDiscovery is a discovery client that counts the calls of the wrapped client, it is wrapped by the discovery
+// cache, so only the calls that are not served by the cache are counted
+type countingDiscovery struct {
+	discovery.ServerResourcesInterface
+}
+
+var _ discovery.ServerResourcesInterface = countingDiscovery{}
+
+// ServerResourcesForGroupVersion returns the supported resources for a group and version
+func (d countingDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	discoveryCalls.WithLabelValues("ServerResourcesForGroupVersion").Inc()
+	return d.ServerResourcesInterface.ServerResourcesForGroupVersion(groupVersion)
+}
+
+// ServerGroupsAndResources returns the supported groups and resources for all groups and versions
+func (d countingDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	discoveryCalls.WithLabelValues("ServerGroupsAndResources").Inc()
+	return d.ServerResourcesInterface.ServerGroupsAndResources()
+}
+
+// ServerPreferredResources returns the supported resources with the version preferred by the server
+func (d countingDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	discoveryCalls.WithLabelValues("ServerPreferredResources").Inc()
+	return d.ServerResourcesInterface.ServerPreferredResources()
+}
+
+// ServerPreferredNamespacedResources returns the supported namespaced resources with the version preferred by the server
+func (d countingDiscovery) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	discoveryCalls.WithLabelValues("ServerPreferredNamespacedResources").Inc()
+	return d.ServerResourcesInterface.ServerPreferredNamespacedResources()
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 
 	"github.com/composable-operator/composable/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -65,3 +67,38 @@ func GetStatusMessage(tContext TestContext, comp *v1alpha1.Composable) func() st
 		return ""
 	}
 }
+
+// GetStatusInputs returns the input objects reported in the status of a Composable object
+func GetStatusInputs(tContext TestContext, comp *v1alpha1.Composable) func() []v1alpha1.InputObjectReference {
+	return func() []v1alpha1.InputObjectReference {
+		if obj := GetObject(tContext, comp)(); obj != nil {
+			c := obj.(*v1alpha1.Composable)
+			return c.Status.Inputs
+		}
+		return nil
+	}
+}
+
+// GetStatusCondition returns the status condition of the given type of a Composable object
+func GetStatusCondition(tContext TestContext, comp *v1alpha1.Composable, conditionType string) func() metav1.Condition {
+	return func() metav1.Condition {
+		if obj := GetObject(tContext, comp)(); obj != nil {
+			c := obj.(*v1alpha1.Composable)
+			if cond := meta.FindStatusCondition(c.Status.Conditions, conditionType); cond != nil {
+				return *cond
+			}
+		}
+		return metav1.Condition{}
+	}
+}
+
+// GetStatusObjects returns the underlying objects reported in the status of a Composable object
+func GetStatusObjects(tContext TestContext, comp *v1alpha1.Composable) func() []v1alpha1.UnderlyingObjectStatus {
+	return func() []v1alpha1.UnderlyingObjectStatus {
+		if obj := GetObject(tContext, comp)(); obj != nil {
+			c := obj.(*v1alpha1.Composable)
+			return c.Status.Objects
+		}
+		return nil
+	}
+}
@@ -58,6 +58,7 @@ func main() {
 	var probeAddr string
 	var syncPeriod time.Duration
 	var queriesPerSecond float32
+	var eventsInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -67,6 +68,7 @@ func main() {
 	flag.DurationVar(&syncPeriod, "sync-period", 60*time.Second, "Sync period")
 	flag.Int("max-concurrent-reconciles", 1, "Maximum number of concurrent reconciles for controllers.")
 	flag.Float32Var(&queriesPerSecond, "queries-per-second", 300.0, "Maximum number of queries per second made by the reconciler client.")
+	flag.DurationVar(&eventsInterval, "events-interval", 5*time.Minute, "Minimal interval between identical events of an object.")
 	viper.BindPFlag("max-concurrent-reconciles", flag.Lookup("max-concurrent-reconciles"))
 	flag.Parse()
 
@@ -92,6 +94,7 @@ func main() {
 
 	reconciler := controllers.NewReconciler(mgr, controllers.ReconcilerOptions{
 		QueriesPerSecond: queriesPerSecond,
+		EventsInterval:   eventsInterval,
 	})
 	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Composable")
@@ -0,0 +1,157 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Aggregation modes of object references, that select input objects by labels
+const (
+	// AggregateList - returns an array of the values of all selected objects
+	AggregateList = "list"
+	// AggregateFirst - returns the value of the first selected object, in the order of the object names
+	AggregateFirst = "first"
+	// AggregateNewest - returns the value of the most recently created selected object
+	AggregateNewest = "newest"
+	// AggregateCount - returns the number of selected objects
+	AggregateCount = "count"
+	// AggregateSum - returns the sum of the numeric values of all selected objects
+	AggregateSum = "sum"
+)
+
+// aggregationModes - all aggregation modes
+var aggregationModes = []string{AggregateList, AggregateFirst, AggregateNewest, AggregateCount, AggregateSum}
+
+// isAggregationMode returns true if the mode is one of the aggregation modes
+func isAggregationMode(mode string) bool {
+	for _, m := range aggregationModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// isAggregation returns true if the mode aggregates the values of all selected objects
+func isAggregation(aggregate string) bool {
+	return aggregate == AggregateList || aggregate == AggregateCount || aggregate == AggregateSum
+}
+
+// selectObject returns the input object whose value is resolved, according to the aggregation mode.
+// Without an aggregation mode exactly one object must be selected.
+func (in *inputObjects) selectObject(aggregate string) (*unstructured.Unstructured, error) {
+	if len(in.items) == 0 || (len(aggregate) == 0 && len(in.items) > 1) {
+		err := &ResolveError{Reason: ReasonObjectNotFound, GroupVersionKind: in.groupVersionKind, Namespace: in.namespace, Message: fmt.Sprintf("list object returned %d items", len(in.items))}
+		logf.Error(err, "wrong # of items", "items", len(in.items), "namespace", in.namespace, "selector", in.selector, "groupVersionKind", in.groupVersionKind)
+		return nil, err
+	}
+	selected := &in.items[0]
+	if aggregate == AggregateNewest {
+		for i := range in.items[1:] {
+			// objects created at the same time are selected by their names
+			item := &in.items[i+1]
+			selectedCreated, itemCreated := selected.GetCreationTimestamp(), item.GetCreationTimestamp()
+			if selectedCreated.Before(&itemCreated) {
+				selected = item
+			}
+		}
+	}
+	return selected, nil
+}
+
+// aggregateValues aggregates the values of all input objects, objects without the value are skipped.
+// The format transformers are applied to the aggregated value.
+func (res *resolution) aggregateValues(ctx context.Context, val map[string]interface{}, aggregate string, objects *inputObjects, composableNamespace string, fldPath *field.Path) (interface{}, error) {
+	refPath, _ := val[path].(string)
+	if expr, ok := val[expression].(string); ok {
+		refPath = expr
+	}
+	if aggregate == AggregateCount {
+		return applyTransformers(val, int64(len(objects.items)), refPath, res.transformers)
+	}
+
+	// the values of the objects are neither transformed nor defaulted
+	objectVal := make(map[string]interface{}, len(val))
+	for k, v := range val {
+		if k != Transformers && k != defaultValue {
+			objectVal[k] = v
+		}
+	}
+	isMulti, _ := val[multi].(bool)
+	values := []interface{}{}
+	for _, item := range objects.items {
+		value, err := res.objectValue(ctx, objectVal, item, composableNamespace, fldPath)
+		if IsValueNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if matches, ok := value.([]interface{}); ok && isMulti {
+			values = append(values, matches...)
+		} else {
+			values = append(values, value)
+		}
+	}
+
+	var aggregated interface{} = values
+	if aggregate == AggregateSum {
+		sum, err := sumValues(values)
+		if err != nil {
+			return nil, fmt.Errorf("sum of %s failed: %w", refPath, err)
+		}
+		aggregated = sum
+	}
+	return applyTransformers(val, aggregated, refPath, res.transformers)
+}
+
+// sumValues returns the sum of numbers or numeric strings, it is an integer unless some value is a float
+func sumValues(values []interface{}) (interface{}, error) {
+	var intSum int64
+	var floatSum float64
+	isFloat := false
+	for _, value := range values {
+		switch v := value.(type) {
+		case int64:
+			intSum += v
+		case uint64:
+			intSum += int64(v)
+		case float64:
+			floatSum += v
+			isFloat = true
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				intSum += i
+			} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+				floatSum += f
+				isFloat = true
+			} else {
+				return nil, fmt.Errorf("the value %q is not a number", v)
+			}
+		default:
+			return nil, fmt.Errorf("the value %v is not a number", value)
+		}
+	}
+	if isFloat {
+		return floatSum + float64(intSum), nil
+	}
+	return intSum, nil
+}
@@ -0,0 +1,128 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Aggregation", func() {
+	var (
+		ctx      context.Context
+		resolver KubernetesResourceResolver
+	)
+
+	created := func(cm *corev1.ConfigMap, minutes int) *corev1.ConfigMap {
+		cm.CreationTimestamp = metav1.NewTime(time.Date(2022, 1, 1, 0, minutes, 0, 0, time.UTC))
+		return cm
+	}
+	labeled := func(aggregate string, ref map[string]interface{}) map[string]interface{} {
+		ref["kind"] = "ConfigMap"
+		ref["labels"] = map[string]interface{}{"app": "db"}
+		ref["aggregate"] = aggregate
+		return ref
+	}
+	resolve := func(ref map[string]interface{}) (interface{}, error) {
+		resolved := map[string]interface{}{}
+		err := resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)
+		if err != nil {
+			return nil, err
+		}
+		return resolved["data"].(map[string]interface{})["value"], nil
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			created(newConfigMap("db-b", map[string]string{"app": "db"}, map[string]string{"host": "b.example.com", "replicas": "2"}), 3),
+			created(newConfigMap("db-a", map[string]string{"app": "db"}, map[string]string{"host": "a.example.com", "replicas": "1"}), 1),
+			created(newConfigMap("db-c", map[string]string{"app": "db"}, map[string]string{"host": "c.example.com"}), 2),
+			newConfigMap("web", map[string]string{"app": "web"}, map[string]string{"host": "web.example.com"}),
+		).Build()
+		resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+	})
+
+	DescribeTable("should aggregate the values of the objects selected by labels",
+		func(ref map[string]interface{}, expected interface{}) {
+			Expect(resolve(ref)).To(Equal(expected))
+		},
+		Entry("list", labeled(AggregateList, map[string]interface{}{"path": "{.data.host}"}),
+			[]interface{}{"a.example.com", "b.example.com", "c.example.com"}),
+		Entry("list skips missing values", labeled(AggregateList, map[string]interface{}{"path": "{.data.replicas}"}),
+			[]interface{}{"1", "2"}),
+		Entry("list of multiple values", labeled(AggregateList, map[string]interface{}{"path": "{.metadata.labels.*}", "multi": true}),
+			[]interface{}{"db", "db", "db"}),
+		Entry("list with transformers", labeled(AggregateList, map[string]interface{}{"path": "{.data.host}", Transformers: []interface{}{ArrayToCSString}}),
+			"a.example.com,b.example.com,c.example.com"),
+		Entry("first", labeled(AggregateFirst, map[string]interface{}{"path": "{.data.host}"}), "a.example.com"),
+		Entry("newest", labeled(AggregateNewest, map[string]interface{}{"path": "{.data.host}"}), "b.example.com"),
+		Entry("count", labeled(AggregateCount, map[string]interface{}{}), float64(3)),
+		Entry("sum", labeled(AggregateSum, map[string]interface{}{"path": "{.data.replicas}"}), float64(3)),
+		Entry("sum of expressions", labeled(AggregateSum, map[string]interface{}{"expression": "size(object.data.host)"}), float64(39)),
+	)
+
+	It("should aggregate an empty set of objects", func() {
+		empty := func(aggregate string, ref map[string]interface{}) map[string]interface{} {
+			ref = labeled(aggregate, ref)
+			ref["labels"] = map[string]interface{}{"app": "none"}
+			return ref
+		}
+		Expect(resolve(empty(AggregateList, map[string]interface{}{"path": "{.data.host}"}))).To(Equal([]interface{}{}))
+		Expect(resolve(empty(AggregateCount, map[string]interface{}{}))).To(Equal(float64(0)))
+		Expect(resolve(empty(AggregateSum, map[string]interface{}{"path": "{.data.replicas}"}))).To(Equal(float64(0)))
+
+		_, err := resolve(empty(AggregateNewest, map[string]interface{}{"path": "{.data.host}"}))
+		Expect(IsObjectNotFound(err)).To(BeTrue())
+		Expect(resolve(empty(AggregateFirst, map[string]interface{}{"path": "{.data.host}", "defaultValue": "localhost"}))).To(Equal("localhost"))
+	})
+
+	It("should require a single object without aggregation", func() {
+		ref := labeled(AggregateList, map[string]interface{}{"path": "{.data.host}"})
+		delete(ref, "aggregate")
+		_, err := resolve(ref)
+		Expect(IsObjectNotFound(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("list object returned 3 items"))
+	})
+
+	It("should fail to sum values that are not numbers", func() {
+		_, err := resolve(labeled(AggregateSum, map[string]interface{}{"path": "{.data.host}"}))
+		Expect(err).To(MatchError(ContainSubstring("is not a number")))
+	})
+
+	It("should validate the aggregation", func() {
+		fldPath := field.NewPath(GetValueFrom)
+		Expect(ValidateReference(labeled(AggregateCount, map[string]interface{}{}), fldPath)).To(Succeed())
+
+		err := ValidateReference(labeled("max", map[string]interface{}{"path": "{.data.host}"}), fldPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("getValueFrom.aggregate"))
+
+		err = ValidateReference(labeled(AggregateCount, map[string]interface{}{"path": "{.data.host}"}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("getValueFrom.path")))
+
+		err = ValidateReference(map[string]interface{}{"kind": "ConfigMap", "name": "db-a", "path": "{.data.host}", "aggregate": AggregateList}, fldPath)
+		Expect(err).To(MatchError(ContainSubstring("only with 'labels'")))
+	})
+})
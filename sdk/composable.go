@@ -3,13 +3,18 @@ package sdk
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/third_party/forked/golang/template"
 	"k8s.io/client-go/util/jsonpath"
@@ -27,8 +32,13 @@ const (
 	kind           = "kind"
 	apiVersion     = "apiVersion"
 	path           = "path"
+	expression     = "expression"
+	multi          = "multi"
+	aggregation    = "aggregate"
 	Name           = "name"
 	Labels         = "labels"
+	LabelSelector  = "labelSelector"
+	FieldSelector  = "fieldSelector"
 	Transformers   = "format-transformers"
 	defaultValue   = "defaultValue"
 	objectPrefix   = ".Object"
@@ -36,54 +46,105 @@ const (
 	objectNotFound = "Error finding an object reference"
 	valueNotFound  = "Error finding a value in an object reference"
 	illFormedRef   = "Object reference is ill-formed"
+	notReady       = "Waiting for an input object to be ready"
 )
 
 // KubernetesResourceResolver implements the ResolveObject interface
 type KubernetesResourceResolver struct {
 	Client          client.Client
 	ResourcesClient discovery.ServerResourcesInterface
+	// Transformers are the format transformers available to object references, DefaultTransformerRegistry is used if nil
+	Transformers *TransformerRegistry
+}
+
+// transformers returns the transformer registry of the resolver
+func (k KubernetesResourceResolver) transformers() *TransformerRegistry {
+	if k.Transformers == nil {
+		return DefaultTransformerRegistry
+	}
+	return k.Transformers
+}
+
+// resolution holds the state of a single ResolveObject call
+type resolution struct {
+	client          client.Client
+	discoveryClient discovery.ServerResourcesInterface
+	transformers    *TransformerRegistry
+	inputs          []InputObject
+	cache           ComposableCache
+	// refs are the named references, and refValues caches their values
+	refs      map[string]interface{}
+	refsPath  *field.Path
+	refValues map[string]interface{}
+	// resolvingRefs holds the named references being resolved, to detect references to themselves
+	resolvingRefs map[string]bool
+	stats         *ResolveStats
 }
 
 // ResolveObject resolves the object into resolved
 func (k KubernetesResourceResolver) ResolveObject(ctx context.Context, object interface{}, resolved interface{}) error {
+	_, err := k.ResolveObjectDependencies(ctx, object, resolved)
+	return err
+}
+
+// InvalidateDiscovery drops the API resources cached by the ResourcesClient, if it caches them
+func (k KubernetesResourceResolver) InvalidateDiscovery() {
+	if cache, ok := k.ResourcesClient.(interface{ Invalidate() }); ok {
+		cache.Invalidate()
+	}
+}
+
+// ResolveObjectDependencies resolves the object into resolved and returns the input objects that were read.
+// The input objects are returned even if the resolution fails, so callers can wait for missing objects to appear.
+func (k KubernetesResourceResolver) ResolveObjectDependencies(ctx context.Context, object interface{}, resolved interface{}) ([]InputObject, error) {
+	return k.ResolveObjectWithOptions(ctx, object, resolved, ResolveOptions{})
+}
+
+// ResolveObjectWithOptions resolves the object into resolved with the given options, and returns the input objects that were read
+func (k KubernetesResourceResolver) ResolveObjectWithOptions(ctx context.Context, object interface{}, resolved interface{}, opts ResolveOptions) ([]InputObject, error) {
 	var objectMap map[string]interface{}
 	inrec, err := json.Marshal(object)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = json.Unmarshal(inrec, &objectMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	namespace, err := GetNamespace(objectMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	result, comperr := resolve(ctx, k.Client, k.ResourcesClient, objectMap, namespace)
+	if opts.Stats == nil {
+		opts.Stats = &ResolveStats{}
+	}
+	res := &resolution{client: k.Client, discoveryClient: k.ResourcesClient, transformers: k.transformers(),
+		refs: opts.Refs, refsPath: opts.RefsPath, stats: opts.Stats}
+	result, comperr := res.resolve(ctx, objectMap, namespace)
 	if comperr != nil {
-		return comperr
+		return res.inputs, comperr
 	}
 
 	inrec, err = json.Marshal(result)
 	if err != nil {
-		return err
+		return res.inputs, err
 	}
 
 	err = json.Unmarshal(inrec, &resolved)
 	if err != nil {
-		return err
+		return res.inputs, err
 	}
 
-	return nil
+	return res.inputs, nil
 }
 
 // Resolve resolves an object and returns an Unstructured
 // This method assumes that the objMap is an object that has a metadata section with a namespace defined
-func resolve(ctx context.Context, r client.Client, discoveryClient discovery.ServerResourcesInterface, objMap map[string]interface{}, defaultNamespace string) (interface{}, error) {
-	obj, err := resolveFields(ctx, r, objMap, defaultNamespace, discoveryClient)
+func (res *resolution) resolve(ctx context.Context, objMap map[string]interface{}, defaultNamespace string) (interface{}, error) {
+	obj, err := res.resolveFields(ctx, objMap, defaultNamespace, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +152,8 @@ func resolve(ctx context.Context, r client.Client, discoveryClient discovery.Ser
 	return obj, nil
 }
 
-func resolveFields(ctx context.Context, r client.Client, fields interface{}, composableNamespace string, discoveryClient discovery.ServerResourcesInterface) (interface{}, error) {
+// resolveFields resolves the object references in fields, fldPath is the location of fields in the resolved object
+func (res *resolution) resolveFields(ctx context.Context, fields interface{}, composableNamespace string, fldPath *field.Path) (interface{}, error) {
 	switch fields.(type) {
 	case map[string]interface{}:
 		if fieldsOut, ok := fields.(map[string]interface{}); ok {
@@ -99,7 +161,7 @@ func resolveFields(ctx context.Context, r client.Client, fields interface{}, com
 				var newFields interface{}
 				var err error
 				if k == GetValueFrom {
-					newFields, err = resolveValue(ctx, r, v, composableNamespace, discoveryClient)
+					newFields, err = res.resolveValue(ctx, v, composableNamespace, fldPath.Child(k))
 					if err != nil {
 						logf.Info("resolveFields resolveValue 1", "err", err)
 						return nil, err
@@ -108,22 +170,28 @@ func resolveFields(ctx context.Context, r client.Client, fields interface{}, com
 				} else if values, ok := v.(map[string]interface{}); ok {
 					if value, ok := values[GetValueFrom]; ok {
 						if len(values) > 1 {
-							err := fmt.Errorf("%s, %s", "GetValueFrom must be the only field in a value", illFormedRef)
+							err := illFormed(fldPath.Child(k), "GetValueFrom must be the only field in a value")
 							logf.Error(err, "resolveFields", "values", values)
 							return nil, err
 						}
-						newFields, err = resolveValue(ctx, r, value, composableNamespace, discoveryClient)
+						newFields, err = res.resolveValue(ctx, value, composableNamespace, fldPath.Child(k, GetValueFrom))
 					} else {
-						newFields, err = resolveFields(ctx, r, values, composableNamespace, discoveryClient)
+						newFields, err = res.resolveFields(ctx, values, composableNamespace, fldPath.Child(k))
 					}
 					if err != nil {
 						logf.Info("resolveFields resolveValue 2", "err", err)
 						return nil, err
 					}
 					fieldsOut[k] = newFields
+				} else if value, ok := v.(string); ok {
+					newFields, err = res.interpolate(ctx, value, composableNamespace, fldPath.Child(k))
+					if err != nil {
+						return nil, err
+					}
+					fieldsOut[k] = newFields
 				} else if values, ok := v.([]interface{}); ok {
 					for i, value := range values {
-						newFields, err := resolveFields(ctx, r, value, composableNamespace, discoveryClient)
+						newFields, err := res.resolveFields(ctx, value, composableNamespace, fldPath.Child(k).Index(i))
 						if err != nil {
 							return nil, err
 						}
@@ -136,13 +204,15 @@ func resolveFields(ctx context.Context, r client.Client, fields interface{}, com
 	case []map[string]interface{}, [][]interface{}:
 		if values, ok := fields.([]interface{}); ok {
 			for i, value := range values {
-				newFields, err := resolveFields(ctx, r, value, composableNamespace, discoveryClient)
+				newFields, err := res.resolveFields(ctx, value, composableNamespace, fldPath.Index(i))
 				if err != nil {
 					return nil, err
 				}
 				values[i] = newFields
 			}
 		}
+	case string:
+		return res.interpolate(ctx, fields.(string), composableNamespace, fldPath)
 	default:
 		return fields, nil
 	}
@@ -177,8 +247,30 @@ func groupQualifiedName(name, group string) string {
 	return fmt.Sprintf("%s.%s", name, group)
 }
 
+// lookupAPIResource finds the API resource of the given kind. If the discovery client caches API resources and the kind
+// is unknown, the cache is dropped and the lookup is repeated, so kinds added after the resources were cached are found.
 func lookupAPIResource(discoveryClient discovery.ServerResourcesInterface, objKind, apiVersion string) (*metav1.APIResource, error) {
 	log.Log.V(1).Info("lookupAPIResource", "objKind", objKind, "apiVersion", apiVersion)
+	apiRes, err := findAPIResource(discoveryClient, objKind, apiVersion)
+	if apiRes == nil && (err == nil || apierrors.IsNotFound(err)) {
+		if cache, ok := discoveryClient.(*CachedResources); ok && cache.invalidateUnknownKind() {
+			log.Log.V(1).Info("lookupAPIResource unknown kind, refresh discovery", "objKind", objKind, "apiVersion", apiVersion)
+			apiRes, err = findAPIResource(discoveryClient, objKind, apiVersion)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiRes == nil {
+		err = fmt.Errorf("Unable to find api resource named %q ", objKind)
+		logf.Error(err, "lookupAPIResource")
+		return nil, err
+	}
+	return apiRes, nil
+}
+
+// findAPIResource looks up the API resource of the given kind, it returns nil without an error if the kind is not found
+func findAPIResource(discoveryClient discovery.ServerResourcesInterface, objKind, apiVersion string) (*metav1.APIResource, error) {
 	var resources []*metav1.APIResourceList
 	var err error
 	if len(apiVersion) > 0 {
@@ -230,172 +322,361 @@ Loop:
 		}
 	}
 	if !coreGroupObject && len(matchedResources) > 1 {
-		err = fmt.Errorf("Multiple resources are matched by %q: %s. A group-qualified plural name must be provided ", kind, strings.Join(matchedResources, ", "))
+		err = fmt.Errorf("Multiple resources are matched by %q: %s. A group-qualified plural name must be provided ", objKind, strings.Join(matchedResources, ", "))
 		logf.Error(err, "lookupAPIResource")
 		return nil, err
 	}
 
-	if targetResource != nil {
-		return targetResource, nil
-	}
-	err = fmt.Errorf("Unable to find api resource named %q ", kind)
-	logf.Error(err, "lookupAPIResource")
-	return nil, err
+	return targetResource, nil
 }
 
-func resolveValue(ctx context.Context, r client.Client, value interface{}, composableNamespace string, discoveryClient discovery.ServerResourcesInterface) (interface{}, error) {
+// resolveValue resolves an object reference, fldPath is the location of the reference in the resolved object
+func (res *resolution) resolveValue(ctx context.Context, value interface{}, composableNamespace string, fldPath *field.Path) (interface{}, error) {
 	// r.log.Info("resolveValue", "value", value)
-	var err error
-	if val, ok := value.(map[string]interface{}); ok {
-		if objKind, ok := val[kind].(string); ok {
-			apiversion := ""
-			if apiversion, ok = val[apiVersion].(string); !ok {
-				apiversion = ""
-			}
-			if path, ok := val[path].(string); ok {
-				if strings.HasPrefix(path, "{.") {
+	if errs := validateReference(value, fldPath, res.transformers); len(errs) > 0 {
+		logf.Error(errs[0], "resolveValue", "value", value)
+		return nil, errs[0]
+	}
+	val := value.(map[string]interface{})
+	objKind := val[kind].(string)
+	apiversion, _ := val[apiVersion].(string)
+	// validateReference has checked that either path or expression is defined
+	refPath, _ := val[path].(string)
+
+	aggregate, _ := val[aggregation].(string)
+	objects, err := res.getInputObjects(ctx, val, objKind, apiversion, composableNamespace)
+	var unstrObj *unstructured.Unstructured
+	if err == nil && !isAggregation(aggregate) {
+		unstrObj, err = objects.selectObject(aggregate)
+	}
+	if err == nil {
+		err = objects.checkReady(val, unstrObj)
+	}
+	if err != nil {
+		err = withReference(err, fldPath, refPath)
+		if IsRefNotFound(err) {
+			// we have checked the object and did not find it
+			val, err1 := errorToDefaultValue(val, err)
+			return val, err1
+		}
+		// we should not be here
+		return nil, err
+	}
+	var resolved interface{}
+	if isAggregation(aggregate) {
+		resolved, err = res.aggregateValues(ctx, val, aggregate, objects, composableNamespace, fldPath)
+	} else {
+		resolved, err = res.objectValue(ctx, val, *unstrObj, composableNamespace, fldPath)
+	}
+	if err != nil {
+		return nil, withReference(err, fldPath, refPath)
+	}
+	res.stats.References++
+	return resolved, nil
+}
 
-					unstrObj, err := getInputObject(ctx, r, val, objKind, apiversion, composableNamespace, discoveryClient)
-					if err != nil {
-						if IsRefNotFound(err) {
-							// we have checked the object and did not find it
-							val, err1 := errorToDefaultValue(val, err)
-							return val, err1
-						}
-						// we should not be here
-						return nil, err
-					}
-					return resolveValue2(val, *unstrObj, path)
+// objectValue returns the value of an object reference in the input object, read either from its path or its expression
+func (res *resolution) objectValue(ctx context.Context, val map[string]interface{}, unstrObj unstructured.Unstructured, composableNamespace string, fldPath *field.Path) (interface{}, error) {
+	if _, ok := val[expression].(string); ok {
+		return res.evaluate(ctx, val, unstrObj, composableNamespace, fldPath)
+	}
+	refPath, _ := val[path].(string)
+	return resolveValue2(val, unstrObj, refPath, res.transformers)
+}
+
+// ValidateReference validates the fields of an object reference (the value of a getValueFrom element) without reading
+// its input object, the format transformers are looked up in the DefaultTransformerRegistry.
+// fldPath is the location of the reference, the returned error joins an IllFormedRef ResolveError for every invalid field.
+func ValidateReference(value interface{}, fldPath *field.Path) error {
+	return KubernetesResourceResolver{}.ValidateReference(value, fldPath)
+}
+
+// ValidateReference validates the fields of an object reference, as ValidateReference does, with the transformers of the resolver
+func (k KubernetesResourceResolver) ValidateReference(value interface{}, fldPath *field.Path) error {
+	return errors.Join(validateReference(value, fldPath, k.transformers())...)
+}
+
+// validateReference returns IllFormedRef errors for the invalid fields of an object reference
+func validateReference(value interface{}, fldPath *field.Path, transformers *TransformerRegistry) []error {
+	val, ok := value.(map[string]interface{})
+	if !ok {
+		return []error{illFormed(fldPath, fmt.Sprintf("value type is not %T", value))}
+	}
+	var errs []error
+	if objKind, ok := val[kind].(string); !ok || len(objKind) == 0 {
+		errs = append(errs, illFormed(fldPath.Child(kind), "'kind' is not defined"))
+	}
+	aggregate, _ := val[aggregation].(string)
+	if aggregate == AggregateCount {
+		if _, ok := val[path]; ok {
+			errs = append(errs, illFormed(fldPath.Child(path), "'path' cannot be defined with the count aggregation"))
+		}
+		if _, ok := val[expression]; ok {
+			errs = append(errs, illFormed(fldPath.Child(expression), "'expression' cannot be defined with the count aggregation"))
+		}
+	} else if expr, ok := val[expression].(string); ok {
+		if refPath, _ := val[path].(string); len(refPath) > 0 {
+			errs = append(errs, illFormed(fldPath, "both 'path' and 'expression' cannot be defined at the same time"))
+		} else if _, err := compileExpression(expr); err != nil {
+			errs = append(errs, illFormed(fldPath.Child(expression), err.Error()))
+		}
+	} else if refPath, ok := val[path].(string); !ok || len(refPath) == 0 {
+		errs = append(errs, illFormed(fldPath.Child(path), "'path' is not defined"))
+	} else if !strings.HasPrefix(refPath, "{.") {
+		errs = append(errs, illFormed(fldPath.Child(path), "'path' is not jsonpath formated"))
+	} else if _, err := parsePath(refPath); err != nil {
+		errs = append(errs, illFormed(fldPath.Child(path), fmt.Sprintf("'path' is not a valid jsonpath: %s", err.Error())))
+	}
+	if isMulti, ok := val[multi]; ok {
+		if _, ok := isMulti.(bool); !ok {
+			errs = append(errs, illFormed(fldPath.Child(multi), "'multi' is not a boolean"))
+		} else if _, ok := val[expression]; ok {
+			errs = append(errs, illFormed(fldPath.Child(multi), "'multi' cannot be used with 'expression', that returns arrays by itself"))
+		}
+	}
+	_, nameOK := val[Name].(string)
+	_, labelsOK := val[Labels].(map[string]interface{})
+	labelSelectorValue, labelSelectorOK := val[LabelSelector]
+	fieldSelectorValue, fieldSelectorOK := val[FieldSelector]
+	selectorOK := labelsOK || labelSelectorOK || fieldSelectorOK
+	if mode, ok := val[aggregation]; ok {
+		if !isAggregationMode(aggregate) {
+			errs = append(errs, illFormed(fldPath.Child(aggregation), fmt.Sprintf("'aggregate' %v is not one of %s", mode, strings.Join(aggregationModes, ", "))))
+		} else if !selectorOK {
+			errs = append(errs, illFormed(fldPath.Child(aggregation), "'aggregate' can be defined only with 'labels', 'labelSelector' or 'fieldSelector'"))
+		}
+	}
+	if nameOK && labelsOK {
+		errs = append(errs, illFormed(fldPath, "both 'name' and 'labels' cannot be defined at the same time"))
+	} else if nameOK && selectorOK {
+		errs = append(errs, illFormed(fldPath, "'name' cannot be defined with 'labelSelector' or 'fieldSelector'"))
+	} else if !nameOK && !selectorOK {
+		errs = append(errs, illFormed(fldPath, "neither 'name' nor 'labels' are defined (one expected)"))
+	}
+	if labelsOK && labelSelectorOK {
+		errs = append(errs, illFormed(fldPath, "both 'labels' and 'labelSelector' cannot be defined at the same time"))
+	}
+	if labelSelectorOK {
+		if _, err := parseLabelSelector(labelSelectorValue); err != nil {
+			errs = append(errs, illFormed(fldPath.Child(LabelSelector), err.Error()))
+		}
+	}
+	if fieldSelectorOK {
+		if fieldSelector, err := parseFieldSelector(fieldSelectorValue); err != nil {
+			errs = append(errs, illFormed(fldPath.Child(FieldSelector), err.Error()))
+		} else if refAPIVersion, _ := val[apiVersion].(string); isCustomResource(refAPIVersion) {
+			errs = append(errs, validateCustomFieldSelector(fieldSelector, fldPath.Child(FieldSelector))...)
+		}
+	}
+	if wait, ok := val[waitFor]; ok {
+		errs = append(errs, validateWaitFor(wait, fldPath.Child(waitFor))...)
+	}
+	if entries, ok := val[Transformers]; ok {
+		trPath := fldPath.Child(Transformers)
+		if entries, ok := entries.([]interface{}); ok {
+			var previous string
+			for i, entry := range entries {
+				// the transformer is created, so its name and arguments are checked
+				ft, err := parseFormatTransformer(entry)
+				if err == nil {
+					_, err = transformers.New(ft)
 				}
-				err = fmt.Errorf("%s, %s ", "GetValueFrom is not well-formed, 'path' is not jsonpath formated", illFormedRef)
-				logf.Error(err, "resolveValue", "path", path)
-				return nil, err
+				if err == nil && len(previous) > 0 {
+					// the transformer should accept the values returned by the previous one
+					err = transformers.checkChain(previous, ft.Name)
+				}
+				if err != nil {
+					errs = append(errs, illFormed(trPath.Index(i), err.Error()))
+					previous = ""
+					continue
+				}
+				previous = ft.Name
 			}
-			err = fmt.Errorf("%s, %s ", "GetValueFrom is not well-formed, 'path' is not defined", illFormedRef)
-			logf.Error(err, "resolveValue", "val", val)
-			return nil, err
+		} else {
+			errs = append(errs, illFormed(trPath, "'format-transformers' is not an array"))
 		}
-		err = fmt.Errorf("%s, %s ", "GetValueFrom is not well-formed, 'kind' is not defined ", illFormedRef)
-		logf.Error(err, "resolveValue", "val", val)
-		return nil, err
 	}
-	err = fmt.Errorf("%s %T, %s ", "GetValueFrom is not well-formed, value type is not ", value, illFormedRef)
-	logf.Error(err, "resolveValue", "value", value)
-	return nil, err
+	return errs
 }
 
-func getInputObject(ctx context.Context, r client.Client, val map[string]interface{}, objKind, apiversion, composableNamespace string, discoveryClient discovery.ServerResourcesInterface) (*unstructured.Unstructured, error) {
-	res, err := lookupAPIResource(discoveryClient, objKind, apiversion)
+// getInputObjects returns the input objects of an object reference, that are read once per resolution
+func (res *resolution) getInputObjects(ctx context.Context, val map[string]interface{}, objKind, apiversion, composableNamespace string) (*inputObjects, error) {
+	apiRes, err := lookupAPIResource(res.discoveryClient, objKind, apiversion)
 	if err != nil {
-		err := fmt.Errorf("%s, %s", err.Error(), kindNotFound)
 		// We cannot resolve input object API resource, so we return error even if a default value is set.
-		return nil, err
+		return nil, &ResolveError{Reason: ReasonKindNotFound, GroupVersionKind: schema.FromAPIVersionAndKind(apiversion, objKind), Err: err}
 	}
-	groupVersionKind := schema.GroupVersionKind{Kind: res.Kind, Version: res.Version, Group: res.Group}
+	groupVersionKind := schema.GroupVersionKind{Kind: apiRes.Kind, Version: apiRes.Version, Group: apiRes.Group}
 	var ns string
 	var ok bool
-	if res.Namespaced {
+	if apiRes.Namespaced {
 		ns, ok = val[Namespace].(string)
 		if !ok {
 			ns = composableNamespace
 		}
 	}
-	name, nameOK := val[Name].(string)
-	intLabels, labelsOK := val[Labels].(map[string]interface{})
-	if nameOK == labelsOK { // only one of them should be defined
-		if nameOK && labelsOK {
-			err = fmt.Errorf("%s, %s", "GetValueFrom is not well-formed, both 'name' and 'labels' cannot be defined at the same time", illFormedRef)
-		} else {
-			err = fmt.Errorf("%s, %s", "GetValueFrom is not well-formed, neither 'name' nor 'labels' are defined (one expected)", illFormedRef)
-		}
-		logf.Error(err, "getInputObject", "val", val)
-		return nil, err
+	// validateReference has checked that either name or selectors are defined
+	name, _ := val[Name].(string)
+	sel, err := referenceSelector(val)
+	if err != nil {
+		return nil, &ResolveError{Reason: ReasonIllFormedRef, GroupVersionKind: groupVersionKind, Namespace: ns, Err: err}
+	}
+	// the input objects are read once per resolution, so all references to an object get the same view of it
+	key := objectKey(name, ns, sel, groupVersionKind)
+	if objects, found, err := res.cache.lookup(key); found {
+		logf.V(1).Info("Input object is cached", "key", key)
+		res.stats.CacheHits++
+		return objects, err
 	}
-	var unstrObj unstructured.Unstructured
-	if nameOK {
+	res.stats.CacheMisses++
+	objects, err := res.readInputObjects(ctx, groupVersionKind, ns, name, sel)
+	res.cache.add(key, objects, err)
+	return objects, err
+}
+
+// readInputObjects reads an input object by its name, or all input objects selected by the selector if it is defined.
+// The namespace is empty for cluster scoped objects.
+func (res *resolution) readInputObjects(ctx context.Context, groupVersionKind schema.GroupVersionKind, ns, name string, sel *objectSelector) (*inputObjects, error) {
+	objects := &inputObjects{groupVersionKind: groupVersionKind, namespace: ns}
+	if sel == nil {
+		var unstrObj unstructured.Unstructured
 		unstrObj.SetGroupVersionKind(groupVersionKind)
-		var objNamespacedname types.NamespacedName
-		if res.Namespaced {
-			objNamespacedname = types.NamespacedName{Namespace: ns, Name: name}
-		} else {
-			objNamespacedname = types.NamespacedName{Name: name}
-		}
+		objNamespacedname := types.NamespacedName{Namespace: ns, Name: name}
 		logf.V(1).Info("Get input object", "obj", objNamespacedname, "groupVersionKind", groupVersionKind)
-		err := r.Get(ctx, objNamespacedname, &unstrObj)
+		res.addInput(InputObject{GroupVersionKind: groupVersionKind, Namespace: objNamespacedname.Namespace, Name: name})
+		err := res.client.Get(ctx, objNamespacedname, &unstrObj)
 		if err != nil {
 			logf.Info("Get object returned ", "err", err, "obj", objNamespacedname)
-			err = fmt.Errorf("%s, %s", err.Error(), objectNotFound)
-			return nil, err
-		}
-	} else { // labelsOK
-		strLabels := make(map[string]string)
-		for key, value := range intLabels {
-			strValue := fmt.Sprintf("%v", value)
-			strLabels[key] = strValue
+			return nil, &ResolveError{Reason: ReasonObjectNotFound, GroupVersionKind: groupVersionKind, Namespace: ns, Name: name, Err: err}
 		}
+		objects.items = []unstructured.Unstructured{unstrObj}
+	} else {
+		res.addInput(sel.input(groupVersionKind, ns))
 		unstrList := unstructured.UnstructuredList{}
 		unstrList.SetGroupVersionKind(groupVersionKind)
-		err = r.List(ctx, &unstrList, client.InNamespace(ns), client.MatchingLabels(strLabels))
+		err := res.client.List(ctx, &unstrList, client.InNamespace(ns), client.MatchingLabelsSelector{Selector: sel.selector()},
+			client.MatchingFieldsSelector{Selector: sel.fieldSelector})
 		if err != nil {
-			logf.Info("list object returned ", "err", err, "namespace", ns, "labels", strLabels, "groupVersionKind", groupVersionKind)
-			err = fmt.Errorf("%s, %s", err.Error(), objectNotFound)
-			return nil, err
+			logf.Info("list object returned ", "err", err, "namespace", ns, "selector", sel.String(), "groupVersionKind", groupVersionKind)
+			return nil, &ResolveError{Reason: ReasonObjectNotFound, GroupVersionKind: groupVersionKind, Namespace: ns, Err: err}
 		}
-		itms := len(unstrList.Items)
-		if itms == 1 {
-			unstrObj = unstrList.Items[0]
-		} else {
-			err = fmt.Errorf("list object returned %d items ", itms)
-			logf.Error(err, "wrong # of items", "items", itms, "namespace", Namespace, "labels", strLabels, "groupVersionKind", groupVersionKind)
-			err = fmt.Errorf("%s, %s", err.Error(), objectNotFound)
-			return nil, err
+		for _, item := range unstrList.Items {
+			if fieldsMatch(&item, sel.fieldSelector) {
+				objects.items = append(objects.items, item)
+			}
+		}
+		// the objects are sorted by name, so the aggregations do not depend on the order of the list
+		sort.Slice(objects.items, func(i, j int) bool { return objects.items[i].GetName() < objects.items[j].GetName() })
+		objects.selector = sel.String()
+	}
+	return objects, nil
+}
+
+// addInput records an input object of the resolution, unless it has already been recorded
+func (res *resolution) addInput(input InputObject) {
+	for _, in := range res.inputs {
+		if reflect.DeepEqual(in, input) {
+			return
 		}
 	}
-	return &unstrObj, nil
+	res.inputs = append(res.inputs, input)
 }
 
-func resolveValue2(val map[string]interface{}, unstrObj unstructured.Unstructured, path string) (interface{}, error) {
+// objectPath returns the jsonpath of a reference path in an unstructured object, whose fields are under ".Object"
+func objectPath(refPath string) string {
+	return refPath[:1] + objectPrefix + refPath[1:]
+}
+
+// parsePath parses the jsonpath of an object reference, it is used both by the resolution and by the validation of
+// the references, so the paths accepted by the validation are resolvable
+func parsePath(refPath string) (*jsonpath.JSONPath, error) {
+	if len(refPath) == 0 {
+		return nil, fmt.Errorf("path is empty")
+	}
 	j := jsonpath.New("compose")
-	// add ".Object" to the path
-	path = path[:1] + objectPrefix + path[1:]
-	err := j.Parse(path)
-	if err != nil {
-		logf.Error(err, "jsonpath.Parse", "path", path)
+	if err := j.Parse(objectPath(refPath)); err != nil {
 		return nil, err
 	}
 	j.AllowMissingKeys(false)
+	return j, nil
+}
 
+func resolveValue2(val map[string]interface{}, unstrObj unstructured.Unstructured, path string, transformers *TransformerRegistry) (interface{}, error) {
+	j, err := parsePath(path)
+	if err != nil {
+		logf.Error(err, "jsonpath.Parse", "path", path)
+		return nil, &ResolveError{Reason: ReasonIllFormedRef, Path: path, Err: err}
+	}
+	objPath := objectPath(path)
+
+	valueNotFoundError := func(err error, message string) *ResolveError {
+		return &ResolveError{Reason: ReasonValueNotFound, GroupVersionKind: unstrObj.GroupVersionKind(),
+			Namespace: unstrObj.GetNamespace(), Name: unstrObj.GetName(), Path: path, Message: message, Err: err}
+	}
 	fullResults, err := j.FindResults(unstrObj)
 	if err != nil {
-		logf.Error(err, "FindResults", "obj", unstrObj, "path", path)
+		logf.Error(err, "FindResults", "obj", unstrObj, "path", objPath)
 		if strings.Contains(err.Error(), "is not found") {
-			err = fmt.Errorf("%s, %s", err.Error(), valueNotFound)
-			return errorToDefaultValue(val, err)
+			return errorToDefaultValue(val, valueNotFoundError(err, ""))
 		}
 		return nil, err
 	}
-	iface, ok := template.PrintableValue(fullResults[0][0])
-	if !ok {
-		err = fmt.Errorf("%s %v, %s", "can't find printable value ", fullResults[0][0], valueNotFound)
-		logf.Error(err, "template.PrintableValue", "obj", unstrObj, "path", path)
-		return nil, err
+	// all matches are returned as an array in the multi mode, otherwise the first match is returned
+	matches := []interface{}{}
+	for _, results := range fullResults {
+		for _, result := range results {
+			iface, ok := template.PrintableValue(result)
+			if !ok {
+				err := valueNotFoundError(nil, fmt.Sprintf("can't find printable value %v", result))
+				logf.Error(err, "template.PrintableValue", "obj", unstrObj, "path", objPath)
+				return nil, err
+			}
+			matches = append(matches, iface)
+		}
+	}
+	if isMulti, _ := val[multi].(bool); isMulti {
+		return applyTransformers(val, matches, path, transformers)
 	}
+	if len(matches) == 0 {
+		return errorToDefaultValue(val, valueNotFoundError(nil, "the path does not match any value"))
+	}
+	return applyTransformers(val, matches[0], path, transformers)
+}
 
-	var retVal interface{}
-	if transformers, ok := val[Transformers].([]interface{}); ok && len(transformers) > 0 {
-		transformNames := make([]string, 0, len(transformers))
-		for _, v := range transformers {
-			if name, ok := v.(string); ok {
-				transformNames = append(transformNames, name)
-			}
+// applyTransformers applies the format transformers of an object reference to the value read from path or expression
+func applyTransformers(val map[string]interface{}, value interface{}, path string, transformers *TransformerRegistry) (interface{}, error) {
+	entries, ok := val[Transformers].([]interface{})
+	if !ok || len(entries) == 0 {
+		return value, nil
+	}
+	formatTransformers := make([]FormatTransformer, 0, len(entries))
+	for _, entry := range entries {
+		ft, err := parseFormatTransformer(entry)
+		if err != nil {
+			return nil, &ResolveError{Reason: ReasonIllFormedRef, Path: path, Err: err}
 		}
-		retVal, err = CompoundTransformerNames(iface, transformNames...)
-	} else {
-		retVal = iface
+		formatTransformers = append(formatTransformers, ft)
+	}
+	retVal, err := transformers.Apply(value, formatTransformers...)
+	if err != nil {
+		err = fmt.Errorf("format transformers of %s failed: %w", path, err)
+		logf.Error(err, "applyTransformers", "transformers", formatTransformers)
+		return nil, err
 	}
 	return retVal, nil
 }
 
+// parseFormatTransformer parses a format transformer, that is either a name or an object with the name and the arguments
+func parseFormatTransformer(entry interface{}) (FormatTransformer, error) {
+	var ft FormatTransformer
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return ft, err
+	}
+	err = json.Unmarshal(data, &ft)
+	return ft, err
+}
+
 func errorToDefaultValue(val map[string]interface{}, err error) (interface{}, error) {
 	if defaultValue, ok := val[defaultValue]; ok {
 		return defaultValue, nil
@@ -403,8 +684,36 @@ func errorToDefaultValue(val map[string]interface{}, err error) (interface{}, er
 	return nil, err
 }
 
-func objectKey(name string, namespace string, labels map[string]interface{}, gvk schema.GroupVersionKind) string {
-	return fmt.Sprintf("%s/%s/%v/%s", name, namespace, labels, gvk.String())
+func objectKey(name string, namespace string, sel *objectSelector, gvk schema.GroupVersionKind) string {
+	selector := ""
+	if sel != nil {
+		selector = sel.String()
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", name, namespace, selector, gvk.String())
+}
+
+// lookup returns cached input objects, or the error of their read if they cannot be read
+func (c *ComposableCache) lookup(key string) (*inputObjects, bool, error) {
+	entry, found := c.objects[key]
+	if !found {
+		return nil, false, nil
+	}
+	if t, ok := entry.(toumbstone); ok {
+		return nil, true, t.err
+	}
+	return entry.(*inputObjects), true, nil
+}
+
+// add caches input objects, or a toumbstone if they cannot be read
+func (c *ComposableCache) add(key string, obj *inputObjects, err error) {
+	if c.objects == nil {
+		c.objects = make(map[string]interface{})
+	}
+	if err != nil {
+		c.objects[key] = toumbstone{err: err}
+		return
+	}
+	c.objects[key] = obj
 }
 
 // GetNamespace gets the namespace out of a map
@@ -415,28 +724,3 @@ func GetNamespace(obj map[string]interface{}) (string, error) {
 	}
 	return "", fmt.Errorf("Failed: Object does not contain namespace")
 }
-
-// IsRefNotFound can be used to determine if an error returned by the ResolvedObject method is due to the reference not being found
-func IsRefNotFound(err error) bool {
-	return IsKindNotFound(err) || IsObjectNotFound(err) || IsValueNotFound(err)
-}
-
-// IsKindNotFound can be used to determine if an error returned by the ResolveObject method is kindNotFound
-func IsKindNotFound(err error) bool {
-	return strings.Contains(err.Error(), kindNotFound)
-}
-
-// IsObjectNotFound can be used to determine if an error returned by the ResolveObject method is objectNotFound
-func IsObjectNotFound(err error) bool {
-	return strings.Contains(err.Error(), objectNotFound)
-}
-
-// IsValueNotFound can be used to determine if an error returned by the ResolveObject method is valueNotFound
-func IsValueNotFound(err error) bool {
-	return strings.Contains(err.Error(), valueNotFound)
-}
-
-// IsIllFormedRef can be used to determine if an error returned by the ResolveObject method is illFormedRef
-func IsIllFormedRef(err error) bool {
-	return strings.Contains(err.Error(), illFormedRef)
-}
@@ -0,0 +1,244 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeResources serves a fixed set of API resources, and counts discovery calls
+type fakeResources struct {
+	lists []*metav1.APIResourceList
+	calls int
+}
+
+func (f *fakeResources) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	f.calls++
+	for _, list := range f.lists {
+		if list.GroupVersion == groupVersion {
+			return list, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Group: groupVersion}, "")
+}
+
+func (f *fakeResources) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	f.calls++
+	return nil, f.lists, nil
+}
+
+func (f *fakeResources) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	f.calls++
+	return f.lists, nil
+}
+
+func (f *fakeResources) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	f.calls++
+	return f.lists, nil
+}
+
+// countingClient counts the reads of objects
+type countingClient struct {
+	client.Client
+	gets  int
+	lists int
+}
+
+func (c *countingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	c.gets++
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *countingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	c.lists++
+	return c.Client.List(ctx, list, opts...)
+}
+
+func newFakeResources() *fakeResources {
+	return &fakeResources{lists: []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", SingularName: "configmap", Namespaced: true, Kind: "ConfigMap", ShortNames: []string{"cm"}},
+			{Name: "secrets", SingularName: "secret", Namespaced: true, Kind: "Secret"},
+			{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", ShortNames: []string{"po"}},
+		},
+	}}}
+}
+
+func newConfigMap(name string, lbls map[string]string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: lbls},
+		Data:       data,
+	}
+}
+
+func newTemplate(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "out", "namespace": "default"},
+		"data":       data,
+	}
+}
+
+func getValueFrom(ref map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{GetValueFrom: ref}
+}
+
+var _ = Describe("KubernetesResourceResolver", func() {
+	var (
+		ctx       context.Context
+		resources *fakeResources
+		cl        *countingClient
+		resolver  KubernetesResourceResolver
+	)
+
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		resources = newFakeResources()
+		cl = &countingClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			newConfigMap("input", map[string]string{"app": "test"}, map[string]string{"host": "example.com", "port": "8080"}),
+		).Build()}
+		resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: resources}
+	})
+
+	Context("ResolveObjectDependencies", func() {
+		It("should resolve values and report the input objects", func() {
+			template := newTemplate(map[string]interface{}{
+				"host": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.host}"}),
+				"port": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.port}"}),
+			})
+			resolved := map[string]interface{}{}
+			inputs, err := resolver.ResolveObjectDependencies(ctx, template, &resolved)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"host": "example.com", "port": "8080"}))
+			Expect(inputs).To(Equal([]InputObject{{GroupVersionKind: configMapGVK, Namespace: "default", Name: "input"}}))
+		})
+
+		It("should report input objects that do not exist", func() {
+			template := newTemplate(map[string]interface{}{
+				"host": getValueFrom(map[string]interface{}{"kind": "Secret", "name": "missing", "path": "{.data.host}"}),
+			})
+			resolved := map[string]interface{}{}
+			inputs, err := resolver.ResolveObjectDependencies(ctx, template, &resolved)
+			Expect(err).To(HaveOccurred())
+			Expect(IsObjectNotFound(err)).To(BeTrue())
+			Expect(inputs).To(Equal([]InputObject{{GroupVersionKind: secretGVK, Namespace: "default", Name: "missing"}}))
+		})
+
+		It("should report input objects selected by labels", func() {
+			template := newTemplate(map[string]interface{}{
+				"host": getValueFrom(map[string]interface{}{"kind": "cm", "labels": map[string]interface{}{"app": "test"}, "path": "{.data.host}"}),
+			})
+			resolved := map[string]interface{}{}
+			inputs, err := resolver.ResolveObjectDependencies(ctx, template, &resolved)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"host": "example.com"}))
+			Expect(inputs).To(Equal([]InputObject{{GroupVersionKind: configMapGVK, Namespace: "default", Labels: map[string]string{"app": "test"}}}))
+		})
+	})
+
+	Context("resolution cache", func() {
+		It("should read an input object once per resolution", func() {
+			template := newTemplate(map[string]interface{}{
+				"host": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.host}"}),
+				"port": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.port}"}),
+				"url":  getValueFrom(map[string]interface{}{"kind": "cm", "name": "input", "namespace": "default", "path": "{.data.host}"}),
+			})
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, template, &resolved)).To(Succeed())
+			Expect(cl.gets).To(Equal(1))
+
+			By("reading the input object again in the next resolution")
+			Expect(resolver.ResolveObject(ctx, template, &resolved)).To(Succeed())
+			Expect(cl.gets).To(Equal(2))
+		})
+
+		It("should read input objects selected by labels once per resolution", func() {
+			template := newTemplate(map[string]interface{}{
+				"host": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "labels": map[string]interface{}{"app": "test"}, "path": "{.data.host}"}),
+				"port": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "labels": map[string]interface{}{"app": "test"}, "path": "{.data.port}"}),
+			})
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, template, &resolved)).To(Succeed())
+			Expect(cl.lists).To(Equal(1))
+		})
+
+		It("should cache input objects that are not found", func() {
+			template := newTemplate(map[string]interface{}{
+				"host": getValueFrom(map[string]interface{}{"kind": "Secret", "name": "missing", "path": "{.data.host}", "defaultValue": "localhost"}),
+				"port": getValueFrom(map[string]interface{}{"kind": "Secret", "name": "missing", "path": "{.data.port}", "defaultValue": "8080"}),
+			})
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, template, &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"host": "localhost", "port": "8080"}))
+			Expect(cl.gets).To(Equal(1))
+		})
+	})
+	Context("multi", func() {
+		It("should return all matches of the path", func() {
+			template := newTemplate(map[string]interface{}{
+				"all":   getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.*}", "multi": true}),
+				"keys":  getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.metadata.labels.*}", "multi": true, Transformers: []interface{}{ArrayToCSString}}),
+				"first": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.metadata.labels.*}"}),
+			})
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, template, &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(HaveKeyWithValue("all", ConsistOf("example.com", "8080")))
+			Expect(resolved["data"]).To(HaveKeyWithValue("keys", "test"))
+			Expect(resolved["data"]).To(HaveKeyWithValue("first", "test"))
+		})
+
+		It("should return an empty array if nothing matches", func() {
+			finalized := newConfigMap("finalized", nil, nil)
+			finalized.Finalizers = []string{"example.com/finalizer"}
+			Expect(cl.Create(ctx, finalized)).To(Succeed())
+			template := newTemplate(map[string]interface{}{
+				"all": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "finalized", "path": "{.metadata.finalizers[?(@==\"other\")]}", "multi": true}),
+			})
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, template, &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"all": []interface{}{}}))
+
+			By("reporting a missing value without multi")
+			template = newTemplate(map[string]interface{}{
+				"first": getValueFrom(map[string]interface{}{"kind": "ConfigMap", "name": "finalized", "path": "{.metadata.finalizers[?(@==\"other\")]}"}),
+			})
+			Expect(IsValueNotFound(resolver.ResolveObject(ctx, template, &resolved))).To(BeTrue())
+		})
+
+		It("should be validated", func() {
+			err := ValidateReference(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data[*]}", "multi": "yes"}, field.NewPath(GetValueFrom))
+			Expect(IsIllFormedRef(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("getValueFrom.multi"))
+			err = ValidateReference(map[string]interface{}{"kind": "ConfigMap", "name": "input", "expression": "object.data", "multi": true}, field.NewPath(GetValueFrom))
+			Expect(IsIllFormedRef(err)).To(BeTrue())
+		})
+	})
+})
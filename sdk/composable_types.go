@@ -1,7 +1,15 @@
 package sdk
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // ComposableCache caches objects that have been read so far in a reconcile cycle
@@ -9,6 +17,16 @@ type ComposableCache struct {
 	objects map[string]interface{}
 }
 
+// inputObjects are the input objects read for an object reference, either a single object read by name, or all
+// objects selected by labels, sorted by name
+type inputObjects struct {
+	groupVersionKind schema.GroupVersionKind
+	namespace        string
+	selector         string
+	items            []unstructured.Unstructured
+}
+
+// toumbstone is cached instead of an object that cannot be read, it keeps the read error
 type toumbstone struct {
 	err error
 }
@@ -19,16 +37,65 @@ type ResolveObject interface {
 	ResolveObject(ctx context.Context, in, out interface{}) error
 }
 
+// DependencyResolver is a ResolveObject that also reports the input objects read during the resolution
+type DependencyResolver interface {
+	ResolveObject
+	// ResolveObjectDependencies resolves object references and returns the input objects they refer to.
+	ResolveObjectDependencies(ctx context.Context, in, out interface{}) ([]InputObject, error)
+}
+
+// OptionsResolver is a DependencyResolver that also accepts options for a resolution, e.g. named object references
+type OptionsResolver interface {
+	DependencyResolver
+	// ResolveObjectWithOptions resolves object references with the given options and returns the input objects they refer to.
+	ResolveObjectWithOptions(ctx context.Context, in, out interface{}, opts ResolveOptions) ([]InputObject, error)
+}
+
+// ResolveStats are the statistics of a single resolution
+type ResolveStats struct {
+	// References is the number of object references that were resolved
+	References int
+	// CacheHits and CacheMisses are the numbers of input object lookups that were served by the cache of the
+	// resolution, and that read the input objects
+	CacheHits   int
+	CacheMisses int
+}
+
+// DiscoveryInvalidator is implemented by resolvers that cache the discovered API resources
+type DiscoveryInvalidator interface {
+	// InvalidateDiscovery drops the cached API resources, e.g. when a CRD is added
+	InvalidateDiscovery()
+}
+
+// InputObject identifies an object, or a set of objects selected by labels, read while resolving object references
+type InputObject struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	// Name is empty if the input objects are selected by Labels, LabelSelector or FieldSelector
+	Name   string
+	Labels map[string]string
+	// LabelSelector and FieldSelector are the selectors of the input objects in their string forms, e.g.
+	// "app in (db,web)" and "status.phase=Running". LabelSelector is empty if the objects are selected by Labels.
+	LabelSelector string
+	FieldSelector string
+}
+
 // ComposableGetValueFrom specifies a reference to a Kubernetes object
 // +kubebuilder:object:generate=true
 type ComposableGetValueFrom struct {
-	Kind               string   `json:"kind"`
-	APIVersion         string   `json:"apiVersion,omitempty"`
-	Name               string   `json:"name,omitempty"`
-	Labels             []string `json:"labels,omitempty"`
-	Namespace          string   `json:"namespace,omitempty"`
-	Path               string   `json:"path"`
-	FormatTransformers []string `json:"format-transformers,omitempty"`
+	Kind               string                `json:"kind"`
+	APIVersion         string                `json:"apiVersion,omitempty"`
+	Name               string                `json:"name,omitempty"`
+	Labels             map[string]string     `json:"labels,omitempty"`
+	LabelSelector      *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	FieldSelector      string                `json:"fieldSelector,omitempty"`
+	Namespace          string                `json:"namespace,omitempty"`
+	Path               string                `json:"path,omitempty"`
+	Expression         string                `json:"expression,omitempty"`
+	Multi              bool                  `json:"multi,omitempty"`
+	Aggregate          string                `json:"aggregate,omitempty"`
+	WaitFor            *ComposableWaitFor    `json:"waitFor,omitempty"`
+	FormatTransformers []FormatTransformer   `json:"format-transformers,omitempty"`
 }
 
 // ObjectRef is the type that can be used for cross-resource references
@@ -36,3 +103,73 @@ type ComposableGetValueFrom struct {
 type ObjectRef struct {
 	GetValueFrom ComposableGetValueFrom `json:"getValueFrom"`
 }
+
+// ComposableWaitFor specifies when the input object is ready to be used, either by a condition in its status.conditions,
+// or by the value matched by a jsonpath
+// +kubebuilder:object:generate=true
+type ComposableWaitFor struct {
+	// Condition is the type of the condition, its Status is "True" if it is not defined
+	Condition string `json:"condition,omitempty"`
+	Status    string `json:"status,omitempty"`
+	// Path is the jsonpath of a value, that must be equal to Value
+	Path  string `json:"path,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// FormatTransformer specifies a format transformer and its arguments.
+// It is represented in JSON either as the transformer name, e.g. "StringToInt", or as an object, e.g. {"name": "Split", "args": [";"]}
+// +kubebuilder:object:generate=true
+type FormatTransformer struct {
+	Name string   `json:"name"`
+	Args []string `json:"args,omitempty"`
+}
+
+// formatTransformer has the object representation of FormatTransformer, with arguments of any scalar type
+type formatTransformer struct {
+	Name string        `json:"name"`
+	Args []interface{} `json:"args,omitempty"`
+}
+
+// UnmarshalJSON accepts either a transformer name, or an object with the name and the arguments of the transformer.
+// Number and boolean arguments are converted to strings, numbers keep their JSON representation, e.g. 1e21 or a large
+// integer are not rounded.
+func (in *FormatTransformer) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*in = FormatTransformer{Name: name}
+		return nil
+	}
+	var ft formatTransformer
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&ft); err != nil {
+		return fmt.Errorf("a format transformer must be either a name or an object with name and args: %w", err)
+	}
+	if len(ft.Name) == 0 {
+		return fmt.Errorf("a format transformer must have a name")
+	}
+	*in = FormatTransformer{Name: ft.Name}
+	for _, arg := range ft.Args {
+		switch arg := arg.(type) {
+		case string:
+			in.Args = append(in.Args, arg)
+		case json.Number:
+			in.Args = append(in.Args, arg.String())
+		case bool:
+			in.Args = append(in.Args, strconv.FormatBool(arg))
+		default:
+			return fmt.Errorf("the arguments of the format transformer %q must be scalar values", ft.Name)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON returns the transformer name if there are no arguments, or an object with the name and the arguments otherwise
+func (in FormatTransformer) MarshalJSON() ([]byte, error) {
+	if len(in.Args) == 0 {
+		return json.Marshal(in.Name)
+	}
+	// plain has the fields of FormatTransformer without its methods
+	type plain FormatTransformer
+	return json.Marshal(plain(in))
+}
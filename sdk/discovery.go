@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+)
+
+// unknownKindRefreshInterval - the minimal interval between cache refreshes caused by lookups of unknown kinds
+const unknownKindRefreshInterval = 10 * time.Second
+
+// CachedResources is a discovery client that caches the discovered API resources until they are invalidated.
+// The resources are discovered lazily, by the first lookup after the invalidation.
+// Only ServerResourcesForGroupVersion and ServerPreferredResources, which are used to look up the input
+// objects kinds, are cached, the other methods are delegated to the wrapped discovery client.
+type CachedResources struct {
+	delegate discovery.ServerResourcesInterface
+
+	lock          sync.Mutex
+	groupVersions map[string]*metav1.APIResourceList
+	preferred     []*metav1.APIResourceList
+	invalidated   time.Time
+}
+
+var _ discovery.ServerResourcesInterface = &CachedResources{}
+
+// NewCachedResources returns a discovery client, that caches the API resources discovered by the given client
+func NewCachedResources(delegate discovery.ServerResourcesInterface) *CachedResources {
+	return &CachedResources{
+		delegate:      delegate,
+		groupVersions: make(map[string]*metav1.APIResourceList),
+	}
+}
+
+// ServerResourcesForGroupVersion returns the cached resources for a group and version
+func (c *CachedResources) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if list, ok := c.groupVersions[groupVersion]; ok {
+		return list, nil
+	}
+	list, err := c.delegate.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		return nil, err
+	}
+	c.groupVersions[groupVersion] = list
+	return list, nil
+}
+
+// ServerGroupsAndResources returns the supported groups and resources for all groups and versions, it is not cached
+func (c *CachedResources) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return c.delegate.ServerGroupsAndResources()
+}
+
+// ServerPreferredResources returns the cached resources with the version preferred by the server.
+// Partial results are not cached.
+func (c *CachedResources) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.preferred != nil {
+		return c.preferred, nil
+	}
+	preferred, err := c.delegate.ServerPreferredResources()
+	if err != nil {
+		return preferred, err
+	}
+	c.preferred = preferred
+	return preferred, nil
+}
+
+// ServerPreferredNamespacedResources returns the namespaced resources with the version preferred by the server,
+// it is not cached
+func (c *CachedResources) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	return c.delegate.ServerPreferredNamespacedResources()
+}
+
+// Invalidate drops the cached resources, so they are discovered again by the next lookup
+func (c *CachedResources) Invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.invalidate()
+}
+
+// invalidateUnknownKind drops the cached resources after a lookup of an unknown kind, unless they were dropped recently.
+// It returns true if the cache was dropped.
+func (c *CachedResources) invalidateUnknownKind() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if time.Since(c.invalidated) < unknownKindRefreshInterval {
+		return false
+	}
+	c.invalidate()
+	return true
+}
+
+func (c *CachedResources) invalidate() {
+	c.groupVersions = make(map[string]*metav1.APIResourceList)
+	c.preferred = nil
+	c.invalidated = time.Now()
+}
@@ -0,0 +1,103 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("CachedResources", func() {
+	var (
+		resources *fakeResources
+		cache     *CachedResources
+	)
+
+	BeforeEach(func() {
+		resources = newFakeResources()
+		resources.lists = append(resources.lists,
+			&metav1.APIResourceList{
+				GroupVersion: "test.ibmcloud.ibm.com/v1",
+				APIResources: []metav1.APIResource{
+					{Name: "configmaps", SingularName: "configmap", Namespaced: true, Kind: "ConfigMap"},
+					{Name: "services", SingularName: "service", Namespaced: true, Kind: "Service"},
+				},
+			},
+			&metav1.APIResourceList{
+				GroupVersion: "ibmcloud.ibm.com/v1alpha1",
+				APIResources: []metav1.APIResource{
+					{Name: "services", SingularName: "service", Namespaced: true, Kind: "Service"},
+				},
+			})
+		cache = NewCachedResources(resources)
+	})
+
+	It("should discover the preferred resources once", func() {
+		for i := 0; i < 3; i++ {
+			apiRes, err := lookupAPIResource(cache, "ConfigMap", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiRes.Group).To(BeEmpty())
+		}
+		Expect(resources.calls).To(Equal(1))
+	})
+
+	It("should discover the resources of a group version once", func() {
+		for i := 0; i < 3; i++ {
+			apiRes, err := lookupAPIResource(cache, "Service", "ibmcloud.ibm.com/v1alpha1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apiRes.Group).To(Equal("ibmcloud.ibm.com"))
+		}
+		Expect(resources.calls).To(Equal(1))
+	})
+
+	It("should keep reporting ambiguous kinds", func() {
+		_, err := lookupAPIResource(cache, "Service", "")
+		Expect(err).To(MatchError(ContainSubstring("Multiple resources are matched by \"Service\"")))
+		Expect(resources.calls).To(Equal(1))
+	})
+
+	It("should refresh the resources once when a kind is unknown", func() {
+		_, err := lookupAPIResource(cache, "InputValue", "")
+		Expect(err).To(MatchError(ContainSubstring("Unable to find api resource named \"InputValue\"")))
+		Expect(resources.calls).To(Equal(2))
+
+		// the resources were refreshed recently, so they are not discovered again
+		_, err = lookupAPIResource(cache, "InputValue", "")
+		Expect(err).To(HaveOccurred())
+		Expect(resources.calls).To(Equal(2))
+	})
+
+	It("should discover kinds added after an invalidation", func() {
+		_, err := lookupAPIResource(cache, "InputValue", "test.ibmcloud.ibm.com/v1")
+		Expect(err).To(HaveOccurred())
+
+		resources.lists[1].APIResources = append(resources.lists[1].APIResources,
+			metav1.APIResource{Name: "inputvalues", SingularName: "inputvalue", Namespaced: true, Kind: "InputValue"})
+		cache.Invalidate()
+		apiRes, err := lookupAPIResource(cache, "InputValue", "test.ibmcloud.ibm.com/v1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiRes.Kind).To(Equal("InputValue"))
+	})
+
+	It("should be invalidated by the resolver", func() {
+		_, err := cache.ServerPreferredResources()
+		Expect(err).NotTo(HaveOccurred())
+		KubernetesResourceResolver{ResourcesClient: cache}.InvalidateDiscovery()
+		_, err = cache.ServerPreferredResources()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resources.calls).To(Equal(2))
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ErrorReason identifies the cause of a ResolveError
+type ErrorReason string
+
+const (
+	// ReasonIllFormedRef - the object reference is ill-formed
+	ReasonIllFormedRef ErrorReason = "IllFormedRef"
+	// ReasonKindNotFound - the kind of the input object cannot be resolved
+	ReasonKindNotFound ErrorReason = "KindNotFound"
+	// ReasonObjectNotFound - the input object cannot be found
+	ReasonObjectNotFound ErrorReason = "ObjectNotFound"
+	// ReasonValueNotFound - the referenced value cannot be found in the input object
+	ReasonValueNotFound ErrorReason = "ValueNotFound"
+	// ReasonNotReady - the input object does not satisfy the waitFor element of the object reference yet
+	ReasonNotReady ErrorReason = "NotReady"
+)
+
+// reasonMessages - the messages that end the errors of every reason
+var reasonMessages = map[ErrorReason]string{
+	ReasonIllFormedRef:   illFormedRef,
+	ReasonKindNotFound:   kindNotFound,
+	ReasonObjectNotFound: objectNotFound,
+	ReasonValueNotFound:  valueNotFound,
+	ReasonNotReady:       notReady,
+}
+
+// Sentinel errors, that can be used with errors.Is to check the reason of an error returned by the ResolveObject method
+var (
+	ErrIllFormedRef   = &ResolveError{Reason: ReasonIllFormedRef}
+	ErrKindNotFound   = &ResolveError{Reason: ReasonKindNotFound}
+	ErrObjectNotFound = &ResolveError{Reason: ReasonObjectNotFound}
+	ErrValueNotFound  = &ResolveError{Reason: ReasonValueNotFound}
+	ErrNotReady       = &ResolveError{Reason: ReasonNotReady}
+)
+
+// ResolveError is returned when an object reference cannot be resolved
+type ResolveError struct {
+	Reason ErrorReason
+	// Message describes the error, it can be empty if Err describes it
+	Message string
+	// GroupVersionKind, Namespace and Name identify the input object, as far as they are known
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	// Path is the jsonpath of the referenced value in the input object
+	Path string
+	// Field is the location of the object reference in the resolved object
+	Field *field.Path
+	// Err is the underlying error
+	Err error
+}
+
+// Error returns the error message, which ends with a description of the reason
+func (e *ResolveError) Error() string {
+	msg := e.Message
+	if e.Err != nil {
+		if len(msg) > 0 {
+			msg = fmt.Sprintf("%s: %s", msg, e.Err.Error())
+		} else {
+			msg = e.Err.Error()
+		}
+	}
+	if e.Field != nil {
+		msg = fmt.Sprintf("%s: %s", e.Field.String(), msg)
+	}
+	if len(msg) == 0 {
+		return reasonMessages[e.Reason]
+	}
+	return fmt.Sprintf("%s, %s", msg, reasonMessages[e.Reason])
+}
+
+// Unwrap returns the underlying error
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is a ResolveError with the same reason, e.g. one of the sentinel errors
+func (e *ResolveError) Is(target error) bool {
+	t, ok := target.(*ResolveError)
+	return ok && t.Reason == e.Reason
+}
+
+// illFormed returns an IllFormedRef error of an object reference field
+func illFormed(fldPath *field.Path, message string) *ResolveError {
+	return &ResolveError{Reason: ReasonIllFormedRef, Message: "GetValueFrom is not well-formed, " + message, Field: fldPath}
+}
+
+// withReference returns a copy of a ResolveError located at the given object reference
+func withReference(err error, fldPath *field.Path, path string) error {
+	var rerr *ResolveError
+	if !errors.As(err, &rerr) {
+		return err
+	}
+	located := *rerr
+	located.Field = fldPath
+	if len(located.Path) == 0 {
+		located.Path = path
+	}
+	return &located
+}
+
+// IsRefNotFound can be used to determine if an error returned by the ResolvedObject method is due to the reference not being found
+func IsRefNotFound(err error) bool {
+	return IsKindNotFound(err) || IsObjectNotFound(err) || IsValueNotFound(err)
+}
+
+// IsKindNotFound can be used to determine if an error returned by the ResolveObject method is kindNotFound
+func IsKindNotFound(err error) bool {
+	return errors.Is(err, ErrKindNotFound)
+}
+
+// IsObjectNotFound can be used to determine if an error returned by the ResolveObject method is objectNotFound
+func IsObjectNotFound(err error) bool {
+	return errors.Is(err, ErrObjectNotFound)
+}
+
+// IsValueNotFound can be used to determine if an error returned by the ResolveObject method is valueNotFound
+func IsValueNotFound(err error) bool {
+	return errors.Is(err, ErrValueNotFound)
+}
+
+// IsNotReady can be used to determine if an error returned by the ResolveObject method is due to an input object,
+// that does not satisfy the waitFor element of its reference yet
+func IsNotReady(err error) bool {
+	return errors.Is(err, ErrNotReady)
+}
+
+// IsIllFormedRef can be used to determine if an error returned by the ResolveObject method is illFormedRef
+func IsIllFormedRef(err error) bool {
+	return errors.Is(err, ErrIllFormedRef)
+}
@@ -0,0 +1,116 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("ResolveError", func() {
+	It("should be matched by its reason, even when it is wrapped", func() {
+		err := fmt.Errorf("reconcile: %w", &ResolveError{Reason: ReasonObjectNotFound, Name: "input"})
+		Expect(errors.Is(err, ErrObjectNotFound)).To(BeTrue())
+		Expect(errors.Is(err, ErrKindNotFound)).To(BeFalse())
+		Expect(IsRefNotFound(err)).To(BeTrue())
+		Expect(IsIllFormedRef(err)).To(BeFalse())
+
+		var resolveErr *ResolveError
+		Expect(errors.As(err, &resolveErr)).To(BeTrue())
+		Expect(resolveErr.Name).To(Equal("input"))
+	})
+
+	It("should describe its location and reason", func() {
+		err := &ResolveError{Reason: ReasonValueNotFound, Field: field.NewPath("data", "host", GetValueFrom), Err: errors.New("host is not found")}
+		Expect(err.Error()).To(Equal("data.host.getValueFrom: host is not found, " + valueNotFound))
+	})
+
+	Context("returned by the resolver", func() {
+		var resolver KubernetesResourceResolver
+
+		BeforeEach(func() {
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				newConfigMap("input", nil, map[string]string{"host": "example.com"}),
+			).Build()
+			resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+		})
+
+		resolve := func(ref map[string]interface{}) *ResolveError {
+			template := newTemplate(map[string]interface{}{"host": getValueFrom(ref)})
+			err := resolver.ResolveObject(context.Background(), template, &map[string]interface{}{})
+			var resolveErr *ResolveError
+			Expect(errors.As(err, &resolveErr)).To(BeTrue())
+			return resolveErr
+		}
+
+		It("should locate ill-formed references", func() {
+			err := resolve(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "data.host"})
+			Expect(err.Reason).To(Equal(ReasonIllFormedRef))
+			Expect(err.Field.String()).To(Equal("data.host.getValueFrom.path"))
+		})
+
+		It("should identify unknown kinds", func() {
+			err := resolve(map[string]interface{}{"kind": "InputValue", "apiVersion": "test.ibmcloud.ibm.com/v1", "name": "input", "path": "{.data.host}"})
+			Expect(err.Reason).To(Equal(ReasonKindNotFound))
+			Expect(err.GroupVersionKind).To(Equal(schema.GroupVersionKind{Group: "test.ibmcloud.ibm.com", Version: "v1", Kind: "InputValue"}))
+			Expect(err.Field.String()).To(Equal("data.host.getValueFrom"))
+		})
+
+		It("should identify missing input objects", func() {
+			err := resolve(map[string]interface{}{"kind": "Secret", "name": "missing", "path": "{.data.host}"})
+			Expect(err.Reason).To(Equal(ReasonObjectNotFound))
+			Expect(err.GroupVersionKind).To(Equal(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}))
+			Expect(err.Namespace).To(Equal("default"))
+			Expect(err.Name).To(Equal("missing"))
+			Expect(err.Path).To(Equal("{.data.host}"))
+		})
+
+		It("should identify missing values", func() {
+			err := resolve(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.port}"})
+			Expect(err.Reason).To(Equal(ReasonValueNotFound))
+			Expect(err.Name).To(Equal("input"))
+			Expect(err.Path).To(Equal("{.data.port}"))
+			Expect(err.Field.String()).To(Equal("data.host.getValueFrom"))
+		})
+	})
+
+	It("should validate all fields of a reference", func() {
+		err := ValidateReference(map[string]interface{}{"name": "input", "labels": map[string]interface{}{"app": "test"}}, field.NewPath("spec", GetValueFrom))
+		Expect(err).To(HaveOccurred())
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.getValueFrom.kind: GetValueFrom is not well-formed, 'kind' is not defined"))
+		Expect(err.Error()).To(ContainSubstring("spec.getValueFrom.path: GetValueFrom is not well-formed, 'path' is not defined"))
+		Expect(err.Error()).To(ContainSubstring("spec.getValueFrom: GetValueFrom is not well-formed, both 'name' and 'labels' cannot be defined at the same time"))
+	})
+
+	It("should parse the jsonpath of a reference", func() {
+		fldPath := field.NewPath("spec", GetValueFrom)
+		Expect(ValidateReference(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.tls\\.key}"}, fldPath)).To(Succeed())
+		Expect(ValidateReference(map[string]interface{}{"kind": "Service", "name": "input", "path": "{.spec.ports[?(@.name==\"http\")].port}"}, fldPath)).To(Succeed())
+		for _, path := range []string{"data.host", "{.data.host", "{.data[}", "{.spec.ports[?(@.name==)]}"} {
+			err := ValidateReference(map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": path}, fldPath)
+			Expect(IsIllFormedRef(err)).To(BeTrue(), path)
+			Expect(err.Error()).To(ContainSubstring("spec.getValueFrom.path: GetValueFrom is not well-formed"), path)
+		}
+	})
+})
@@ -0,0 +1,355 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+const (
+	// objectVariable - the CEL variable that holds the input object
+	objectVariable = "object"
+	// refsVariable - the CEL variable that holds the values of the named references
+	refsVariable = "refs"
+	// indexFunction - the CEL function of the index operator
+	indexFunction = "_[_]"
+	// expressionCostLimit - the maximal cost of the evaluation of an expression, the limit of an expression of the
+	// validation rules of Kubernetes, so an expression over large lists cannot block the reconciliation
+	expressionCostLimit = 1000000
+	// expressionInterruptCheckFrequency - the number of comprehension iterations between checks of the cancellation
+	// of the reconciliation
+	expressionInterruptCheckFrequency = 100
+)
+
+var (
+	// identifierPattern - the pattern of the field names that can be selected with a dot in an expression
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	celEnv     *cel.Env
+	celEnvErr  error
+	celEnvOnce sync.Once
+)
+
+// expressionEnv returns the CEL environment of expressions, it declares the input object and the named references
+func expressionEnv() (*cel.Env, error) {
+	celEnvOnce.Do(func() {
+		celEnv, celEnvErr = cel.NewEnv(
+			cel.Variable(objectVariable, cel.DynType),
+			cel.Variable(refsVariable, cel.MapType(cel.StringType, cel.DynType)),
+			ext.Strings(),
+		)
+	})
+	return celEnv, celEnvErr
+}
+
+// compileExpression parses and type-checks a CEL expression
+func compileExpression(expr string) (*cel.Ast, error) {
+	env, err := expressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	return ast, nil
+}
+
+// evaluate evaluates the CEL expression of an object reference against its input object and the named references
+func (res *resolution) evaluate(ctx context.Context, val map[string]interface{}, unstrObj unstructured.Unstructured, composableNamespace string, fldPath *field.Path) (interface{}, error) {
+	expr := val[expression].(string)
+	ast, err := compileExpression(expr)
+	if err != nil {
+		return nil, &ResolveError{Reason: ReasonIllFormedRef, Message: "the expression cannot be compiled", Err: err}
+	}
+	env, _ := expressionEnv()
+	prg, err := env.Program(ast, cel.CostLimit(expressionCostLimit), cel.InterruptCheckFrequency(expressionInterruptCheckFrequency))
+	if err != nil {
+		return nil, &ResolveError{Reason: ReasonIllFormedRef, Message: "the expression cannot be compiled", Err: err}
+	}
+
+	names, all := expressionRefs(ast.Expr())
+	if all {
+		names = names[:0]
+		for name := range res.refs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	refValues := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		value, err := res.refValue(ctx, name, composableNamespace, fldPath.Child(expression))
+		if err != nil {
+			return nil, err
+		}
+		refValues[name] = value
+	}
+
+	vars := map[string]interface{}{objectVariable: unstrObj.Object, refsVariable: refValues}
+	out, _, err := prg.ContextEval(ctx, vars)
+	if err != nil {
+		// the evaluation fails if a selected value is missing, unless the expression tests it with has()
+		if missing := missingValue(vars, selectedValues(ast.Expr())); len(missing) > 0 {
+			return errorToDefaultValue(val, &ResolveError{Reason: ReasonValueNotFound, GroupVersionKind: unstrObj.GroupVersionKind(),
+				Namespace: unstrObj.GetNamespace(), Name: unstrObj.GetName(), Message: missing + " is not found", Err: err})
+		}
+		err = fmt.Errorf("expression %q failed: %w", expr, err)
+		logf.Error(err, "evaluate", "obj", unstrObj)
+		return nil, err
+	}
+	value, err := celToNative(out)
+	if err != nil {
+		return nil, fmt.Errorf("the value of expression %q cannot be converted: %w", expr, err)
+	}
+	return applyTransformers(val, value, expr, res.transformers)
+}
+
+// expressionRefs returns the names of the named references selected by an expression, e.g. refs.host or refs["host"].
+// all is true if the expression uses the references in another way, e.g. "host" in refs, so it depends on all of them.
+func expressionRefs(e *exprpb.Expr) (names []string, all bool) {
+	isRefs := func(e *exprpb.Expr) bool {
+		return e.GetIdentExpr() != nil && e.GetIdentExpr().GetName() == refsVariable
+	}
+	var walk func(e *exprpb.Expr)
+	walk = func(e *exprpb.Expr) {
+		if e == nil {
+			return
+		}
+		switch kind := e.GetExprKind().(type) {
+		case *exprpb.Expr_IdentExpr:
+			all = all || isRefs(e)
+		case *exprpb.Expr_SelectExpr:
+			if isRefs(kind.SelectExpr.GetOperand()) {
+				names = append(names, kind.SelectExpr.GetField())
+			} else {
+				walk(kind.SelectExpr.GetOperand())
+			}
+		case *exprpb.Expr_CallExpr:
+			args := kind.CallExpr.GetArgs()
+			if kind.CallExpr.GetFunction() == indexFunction && len(args) == 2 && isRefs(args[0]) &&
+				args[1].GetConstExpr() != nil && args[1].GetConstExpr().GetStringValue() != "" {
+				names = append(names, args[1].GetConstExpr().GetStringValue())
+				return
+			}
+			walk(kind.CallExpr.GetTarget())
+			for _, arg := range args {
+				walk(arg)
+			}
+		case *exprpb.Expr_ListExpr:
+			for _, element := range kind.ListExpr.GetElements() {
+				walk(element)
+			}
+		case *exprpb.Expr_StructExpr:
+			for _, entry := range kind.StructExpr.GetEntries() {
+				walk(entry.GetMapKey())
+				walk(entry.GetValue())
+			}
+		case *exprpb.Expr_ComprehensionExpr:
+			comprehension := kind.ComprehensionExpr
+			walk(comprehension.GetIterRange())
+			walk(comprehension.GetAccuInit())
+			walk(comprehension.GetLoopCondition())
+			walk(comprehension.GetLoopStep())
+			walk(comprehension.GetResult())
+		}
+	}
+	walk(e)
+	return names, all
+}
+
+// selectedValues returns the values that an expression selects from the input object and the named references, by
+// field names and constant indexes, e.g. object.spec.host or refs["db"].ports[0], as the variable name followed by the
+// keys and indexes. The value tested by has() is optional, so only its parent is returned.
+func selectedValues(e *exprpb.Expr) [][]interface{} {
+	var selection func(e *exprpb.Expr) ([]interface{}, bool)
+	selection = func(e *exprpb.Expr) ([]interface{}, bool) {
+		switch kind := e.GetExprKind().(type) {
+		case *exprpb.Expr_IdentExpr:
+			name := kind.IdentExpr.GetName()
+			return []interface{}{name}, name == objectVariable || name == refsVariable
+		case *exprpb.Expr_SelectExpr:
+			if kind.SelectExpr.GetTestOnly() {
+				return nil, false
+			}
+			operand, ok := selection(kind.SelectExpr.GetOperand())
+			return append(operand, kind.SelectExpr.GetField()), ok
+		case *exprpb.Expr_CallExpr:
+			args := kind.CallExpr.GetArgs()
+			if kind.CallExpr.GetFunction() != indexFunction || len(args) != 2 || args[1].GetConstExpr() == nil {
+				return nil, false
+			}
+			operand, ok := selection(args[0])
+			switch key := args[1].GetConstExpr().GetConstantKind().(type) {
+			case *exprpb.Constant_StringValue:
+				return append(operand, key.StringValue), ok
+			case *exprpb.Constant_Int64Value:
+				return append(operand, int(key.Int64Value)), ok
+			case *exprpb.Constant_Uint64Value:
+				return append(operand, int(key.Uint64Value)), ok
+			}
+		}
+		return nil, false
+	}
+
+	var selected [][]interface{}
+	var walk func(e *exprpb.Expr)
+	walk = func(e *exprpb.Expr) {
+		if e == nil {
+			return
+		}
+		if path, ok := selection(e); ok && len(path) > 1 {
+			selected = append(selected, path)
+		}
+		switch kind := e.GetExprKind().(type) {
+		case *exprpb.Expr_SelectExpr:
+			walk(kind.SelectExpr.GetOperand())
+		case *exprpb.Expr_CallExpr:
+			walk(kind.CallExpr.GetTarget())
+			for _, arg := range kind.CallExpr.GetArgs() {
+				walk(arg)
+			}
+		case *exprpb.Expr_ListExpr:
+			for _, element := range kind.ListExpr.GetElements() {
+				walk(element)
+			}
+		case *exprpb.Expr_StructExpr:
+			for _, entry := range kind.StructExpr.GetEntries() {
+				walk(entry.GetMapKey())
+				walk(entry.GetValue())
+			}
+		case *exprpb.Expr_ComprehensionExpr:
+			comprehension := kind.ComprehensionExpr
+			walk(comprehension.GetIterRange())
+			walk(comprehension.GetAccuInit())
+			walk(comprehension.GetLoopCondition())
+			walk(comprehension.GetLoopStep())
+			walk(comprehension.GetResult())
+		}
+	}
+	walk(e)
+	return selected
+}
+
+// missingValue returns the first selected value that is missing in the variables of an expression, e.g.
+// object.data.user, or an empty string if all of them are found. A value that is selected from a value of another type,
+// e.g. a field of a string, is not missing, the evaluation reports the type error.
+func missingValue(vars map[string]interface{}, selected [][]interface{}) string {
+	for _, path := range selected {
+		var value interface{} = vars
+		for i, key := range path {
+			next, found, selectable := selectValue(value, key)
+			if !selectable {
+				break
+			}
+			if !found {
+				return formatSelection(path[:i+1])
+			}
+			value = next
+		}
+	}
+	return ""
+}
+
+// selectValue selects the value of a key of a map, or of an index of a list. selectable is false if the value is
+// neither a map with string keys nor a list, or if the type of the key does not match it.
+func selectValue(value interface{}, key interface{}) (selected interface{}, found bool, selectable bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			selected, found = v[k]
+			return selected, found, true
+		}
+	case []interface{}:
+		if index, ok := key.(int); ok {
+			if index < 0 || index >= len(v) {
+				return nil, false, true
+			}
+			return v[index], true, true
+		}
+	}
+	return nil, false, false
+}
+
+// formatSelection formats a selected value as it is written in an expression, e.g. object.data.user or
+// object.metadata.labels["app.kubernetes.io/name"]
+func formatSelection(path []interface{}) string {
+	var b strings.Builder
+	for i, key := range path {
+		switch k := key.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", k)
+		case string:
+			switch {
+			case i == 0:
+				b.WriteString(k)
+			case identifierPattern.MatchString(k):
+				b.WriteString("." + k)
+			default:
+				fmt.Fprintf(&b, "[%q]", k)
+			}
+		}
+	}
+	return b.String()
+}
+
+// celToNative converts a CEL value to the JSON compatible value of an unstructured object
+func celToNative(val ref.Val) (interface{}, error) {
+	switch v := val.(type) {
+	case types.Null:
+		return nil, nil
+	case traits.Mapper:
+		out := make(map[string]interface{})
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			k, ok := key.Value().(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a string", key.Value())
+			}
+			item, err := celToNative(v.Get(key))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = item
+		}
+		return out, nil
+	case traits.Lister:
+		out := []interface{}{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			item, err := celToNative(it.Next())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+		}
+		return out, nil
+	default:
+		return val.Value(), nil
+	}
+}
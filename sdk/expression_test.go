@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Expressions", func() {
+	var (
+		ctx      context.Context
+		resolver KubernetesResourceResolver
+	)
+
+	expressionRef := func(expr string) map[string]interface{} {
+		return map[string]interface{}{"kind": "ConfigMap", "name": "db", "expression": expr}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			newConfigMap("db", map[string]string{"tier": "backend"}, map[string]string{"port": "5432", "host": "db-1.example.com"}),
+		).Build()
+		resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+	})
+
+	DescribeTable("should evaluate the expression against the input object",
+		func(expr string, expected interface{}) {
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(expressionRef(expr))}), &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"value": expected}))
+		},
+		Entry("string", `object.data.host`, "db-1.example.com"),
+		Entry("arithmetic", `int(object.data.port) + 1`, float64(5433)),
+		Entry("conditional", `object.metadata.labels.tier == "backend" ? "internal" : "external"`, "internal"),
+		Entry("regex", `object.data.host.matches("^db-[0-9]+\\.")`, true),
+		Entry("list", `object.data.host.split(".")`, []interface{}{"db-1", "example", "com"}),
+		Entry("map", `{"port": object.data.port}`, map[string]interface{}{"port": "5432"}),
+	)
+
+	It("should apply the format transformers to the value of the expression", func() {
+		ref := expressionRef(`object.data.host.split(".")`)
+		ref[Transformers] = []interface{}{map[string]interface{}{"name": Join, "args": []interface{}{"-"}}}
+		resolved := map[string]interface{}{}
+		Expect(resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)).To(Succeed())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"value": "db-1-example-com"}))
+	})
+
+	It("should report missing values, unless there is a default value", func() {
+		ref := expressionRef(`object.data.user`)
+		resolved := map[string]interface{}{}
+		err := resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)
+		Expect(IsValueNotFound(err)).To(BeTrue())
+
+		ref[defaultValue] = "admin"
+		Expect(resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)).To(Succeed())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"value": "admin"}))
+
+		By("reporting the missing value")
+		err = resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(expressionRef(`object.metadata.labels["app.kubernetes.io/name"]`))}), &resolved)
+		Expect(IsValueNotFound(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`object.metadata.labels["app.kubernetes.io/name"] is not found`))
+
+		By("accepting missing values that are tested by has()")
+		ref = expressionRef(`has(object.data.user) ? object.data.user : "nobody"`)
+		Expect(resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)).To(Succeed())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"value": "nobody"}))
+
+		By("not reporting other errors as missing values")
+		err = resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(expressionRef(`int(object.data.host)`))}), &resolved)
+		Expect(err).To(HaveOccurred())
+		Expect(IsValueNotFound(err)).To(BeFalse())
+	})
+
+	It("should stop the evaluation of expressions that are too expensive or cancelled", func() {
+		nested := func(size int) string {
+			items := make([]string, size)
+			for i := range items {
+				items[i] = strconv.Itoa(i)
+			}
+			list := "[" + strings.Join(items, ",") + "]"
+			return fmt.Sprintf("%[1]s.map(a, %[1]s.map(b, %[1]s.map(c, a + b + c)))", list)
+		}
+		resolved := map[string]interface{}{}
+		err := resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(expressionRef(nested(200)))}), &resolved)
+		Expect(err).To(MatchError(ContainSubstring("cost limit exceeded")))
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		err = resolver.ResolveObject(cancelled, newTemplate(map[string]interface{}{"value": getValueFrom(expressionRef(nested(40)))}), &resolved)
+		Expect(err).To(MatchError(ContainSubstring("operation interrupted")))
+	})
+
+	It("should evaluate the expression against the named references", func() {
+		refs := map[string]interface{}{
+			"port":   map[string]interface{}{"kind": "ConfigMap", "name": "db", "path": "{.data.port}", Transformers: []interface{}{StringToInt}},
+			"scheme": expressionRef(`refs.port == 443 ? "https" : "http"`),
+			"self":   expressionRef(`refs.self`),
+		}
+		object := newTemplate(map[string]interface{}{
+			"url": getValueFrom(expressionRef(`refs["scheme"] + "://" + object.data.host + ":" + string(refs.port)`)),
+		})
+		resolved := map[string]interface{}{}
+		_, err := resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: field.NewPath("refs")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"url": "http://db-1.example.com:5432"}))
+
+		By("rejecting references to themselves")
+		object = newTemplate(map[string]interface{}{"value": "${self}"})
+		_, err = resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: field.NewPath("refs")})
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("refs.self"))
+	})
+
+	It("should validate the expressions", func() {
+		fldPath := field.NewPath(GetValueFrom)
+		Expect(ValidateReference(expressionRef(`object.data.port`), fldPath)).To(Succeed())
+
+		err := ValidateReference(expressionRef(`object.data.port +`), fldPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("getValueFrom.expression"))
+
+		err = ValidateReference(expressionRef(`unknown.data`), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("undeclared reference to 'unknown'")))
+
+		ref := expressionRef(`object.data.port`)
+		ref[path] = "{.data.port}"
+		err = ValidateReference(ref, fldPath)
+		Expect(err).To(MatchError(ContainSubstring("both 'path' and 'expression'")))
+
+		err = ValidatePlaceholders(newTemplate(map[string]interface{}{"value": getValueFrom(expressionRef(`refs.port`))}), nil, field.NewPath("template"))
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("template.data.value.getValueFrom.expression"))
+	})
+
+	It("should find the named references of an expression", func() {
+		ast, err := compileExpression(`refs.a + refs["b"] + object.data[refs.c] + [1].map(x, refs.d)[0]`)
+		Expect(err).NotTo(HaveOccurred())
+		names, all := expressionRefs(ast.Expr())
+		Expect(names).To(ConsistOf("a", "b", "c", "d"))
+		Expect(all).To(BeFalse())
+
+		ast, err = compileExpression(`"a" in refs`)
+		Expect(err).NotTo(HaveOccurred())
+		_, all = expressionRefs(ast.Expr())
+		Expect(all).To(BeTrue())
+	})
+})
@@ -0,0 +1,211 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	// placeholderRegexp matches the ${name} placeholders of named references, and the escaped placeholders $${
+	placeholderRegexp = regexp.MustCompile(`\$\$\{|\$\{([^{}]*)\}`)
+
+	// refNameRegexp - the format of the names of named references
+	refNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+)
+
+// ResolveOptions are the options of a single resolution
+type ResolveOptions struct {
+	// Refs declares named object references, that have the format of getValueFrom elements. If it is not empty, every
+	// ${name} placeholder in the strings of the resolved object is replaced by the value of the reference with that name.
+	Refs map[string]interface{}
+	// RefsPath is the location of Refs, the errors of the named references refer to it
+	RefsPath *field.Path
+	// Stats, if it is not nil, is filled with the statistics of the resolution, e.g. to report them as metrics
+	Stats *ResolveStats
+}
+
+// interpolate replaces the placeholders of a string by the values of the named references.
+// A string that consists of a single placeholder is replaced by the value itself, so the value keeps its type.
+func (res *resolution) interpolate(ctx context.Context, s string, composableNamespace string, fldPath *field.Path) (interface{}, error) {
+	if len(res.refs) == 0 || !strings.Contains(s, "${") {
+		return s, nil
+	}
+	matches := placeholderRegexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) && matches[0][2] >= 0 {
+		return res.refValue(ctx, s[matches[0][2]:matches[0][3]], composableNamespace, fldPath)
+	}
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		out.WriteString(s[last:match[0]])
+		last = match[1]
+		if match[2] < 0 {
+			// escaped placeholder
+			out.WriteString("${")
+			continue
+		}
+		value, err := res.refValue(ctx, s[match[2]:match[3]], composableNamespace, fldPath)
+		if err != nil {
+			return nil, err
+		}
+		str, err := interpolatedString(value)
+		if err != nil {
+			return nil, &ResolveError{Reason: ReasonIllFormedRef, Message: fmt.Sprintf("the value of ${%s} cannot be formatted", s[match[2]:match[3]]), Field: fldPath, Err: err}
+		}
+		out.WriteString(str)
+	}
+	out.WriteString(s[last:])
+	return out.String(), nil
+}
+
+// refValue returns the value of a named reference, every reference is resolved once per resolution
+func (res *resolution) refValue(ctx context.Context, name string, composableNamespace string, fldPath *field.Path) (interface{}, error) {
+	ref, ok := res.refs[name]
+	if !ok {
+		return nil, &ResolveError{Reason: ReasonIllFormedRef, Message: fmt.Sprintf("the reference %q is not declared", name), Field: fldPath}
+	}
+	if value, ok := res.refValues[name]; ok {
+		return value, nil
+	}
+	if res.resolvingRefs[name] {
+		return nil, &ResolveError{Reason: ReasonIllFormedRef, Message: fmt.Sprintf("the expression of %q refers to itself", name), Field: res.refsPath.Child(name)}
+	}
+	if res.resolvingRefs == nil {
+		res.resolvingRefs = make(map[string]bool)
+	}
+	res.resolvingRefs[name] = true
+	value, err := res.resolveValue(ctx, ref, composableNamespace, res.refsPath.Child(name))
+	delete(res.resolvingRefs, name)
+	if err != nil {
+		return nil, err
+	}
+	if res.refValues == nil {
+		res.refValues = make(map[string]interface{})
+	}
+	res.refValues[name] = value
+	return value, nil
+}
+
+// interpolatedString formats a value inserted into a string, objects and arrays are formatted as JSON
+func interpolatedString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// ValidateRefs validates the names and the fields of named object references without reading their input objects,
+// the format transformers are looked up in the DefaultTransformerRegistry.
+// fldPath is the location of the references, the returned error joins an IllFormedRef ResolveError for every invalid field.
+func ValidateRefs(refs map[string]interface{}, fldPath *field.Path) error {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if !refNameRegexp.MatchString(name) {
+			errs = append(errs, &ResolveError{Reason: ReasonIllFormedRef, Field: fldPath.Key(name),
+				Message: fmt.Sprintf("the reference name must match %s", refNameRegexp.String())})
+			continue
+		}
+		refErrs := validateReference(refs[name], fldPath.Child(name), DefaultTransformerRegistry)
+		if len(refErrs) == 0 {
+			refErrs = validateExpressionRefs(refs[name], refs, fldPath.Child(name))
+		}
+		errs = append(errs, refErrs...)
+	}
+	return errors.Join(errs...)
+}
+
+// ValidatePlaceholders checks that the placeholders in the strings of the object, and the expressions of its getValueFrom
+// elements refer to the named references. fldPath is the location of the object, the returned error joins an IllFormedRef
+// ResolveError for every invalid field. The strings are not interpolated if there are no named references, so they are
+// not checked either.
+func ValidatePlaceholders(object interface{}, refs map[string]interface{}, fldPath *field.Path) error {
+	return errors.Join(validatePlaceholders(object, refs, fldPath)...)
+}
+
+// validateExpressionRefs checks that the expression of an object reference, if it has one, refers to declared references
+func validateExpressionRefs(value interface{}, refs map[string]interface{}, fldPath *field.Path) []error {
+	val, _ := value.(map[string]interface{})
+	expr, ok := val[expression].(string)
+	if !ok {
+		return nil
+	}
+	ast, err := compileExpression(expr)
+	if err != nil {
+		// the expression is validated by validateReference
+		return nil
+	}
+	var errs []error
+	names, _ := expressionRefs(ast.Expr())
+	for _, name := range names {
+		if refs[name] == nil {
+			errs = append(errs, &ResolveError{Reason: ReasonIllFormedRef, Field: fldPath.Child(expression),
+				Message: fmt.Sprintf("the reference %q is not declared", name)})
+		}
+	}
+	return errs
+}
+
+func validatePlaceholders(object interface{}, refs map[string]interface{}, fldPath *field.Path) []error {
+	var errs []error
+	switch value := object.(type) {
+	case string:
+		if len(refs) == 0 {
+			break
+		}
+		for _, match := range placeholderRegexp.FindAllStringSubmatchIndex(value, -1) {
+			if match[2] < 0 {
+				continue
+			}
+			if name := value[match[2]:match[3]]; refs[name] == nil {
+				errs = append(errs, &ResolveError{Reason: ReasonIllFormedRef, Field: fldPath,
+					Message: fmt.Sprintf("the placeholder ${%s} refers to an undeclared reference", name)})
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range value {
+			if k == GetValueFrom {
+				errs = append(errs, validateExpressionRefs(v, refs, fldPath.Child(k))...)
+			} else {
+				errs = append(errs, validatePlaceholders(v, refs, fldPath.Child(k))...)
+			}
+		}
+	case []interface{}:
+		for i, v := range value {
+			errs = append(errs, validatePlaceholders(v, refs, fldPath.Index(i))...)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Interpolation", func() {
+	var (
+		ctx      context.Context
+		cl       *countingClient
+		resolver KubernetesResourceResolver
+		refs     map[string]interface{}
+		refsPath *field.Path
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cl = &countingClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			newConfigMap("db", nil, map[string]string{"user": "admin", "host": "db.example.com", "port": "5432"}),
+		).Build()}
+		resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+		refs = map[string]interface{}{
+			"user": map[string]interface{}{"kind": "ConfigMap", "name": "db", "path": "{.data.user}"},
+			"host": map[string]interface{}{"kind": "ConfigMap", "name": "db", "path": "{.data.host}"},
+			"port": map[string]interface{}{"kind": "ConfigMap", "name": "db", "path": "{.data.port}", Transformers: []interface{}{StringToInt}},
+		}
+		refsPath = field.NewPath("refs")
+	})
+
+	It("should expand the placeholders of named references", func() {
+		object := newTemplate(map[string]interface{}{
+			"url":     "postgres://${user}@${host}:${port}/db",
+			"port":    "${port}",
+			"escaped": "$${user} is ${user}",
+			"hosts":   []interface{}{"${host}", "backup.${host}"},
+		})
+		resolved := map[string]interface{}{}
+		_, err := resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: refsPath})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{
+			"url":     "postgres://admin@db.example.com:5432/db",
+			"port":    float64(5432),
+			"escaped": "${user} is admin",
+			"hosts":   []interface{}{"db.example.com", "backup.db.example.com"},
+		}))
+		Expect(cl.gets).To(Equal(1))
+	})
+
+	It("should report the statistics of the resolution", func() {
+		object := newTemplate(map[string]interface{}{"url": "postgres://${user}@${host}:${port}/db", "port": "${port}"})
+		resolved := map[string]interface{}{}
+		stats := ResolveStats{}
+		_, err := resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: refsPath, Stats: &stats})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(Equal(ResolveStats{References: 3, CacheHits: 2, CacheMisses: 1}))
+	})
+
+	It("should not change the strings if there are no named references", func() {
+		object := newTemplate(map[string]interface{}{"script": "echo ${HOME} $${USER}"})
+		resolved := map[string]interface{}{}
+		Expect(resolver.ResolveObject(ctx, object, &resolved)).To(Succeed())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"script": "echo ${HOME} $${USER}"}))
+	})
+
+	It("should interpret literal ${ strings as placeholders once named references are declared", func() {
+		object := newTemplate(map[string]interface{}{"script": "echo ${HOME}", "user": "${user}"})
+		resolved := map[string]interface{}{}
+		Expect(resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"script": "echo ${HOME}"}), &resolved)).To(Succeed())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"script": "echo ${HOME}"}))
+
+		By("rejecting the literal value, that is not a declared reference")
+		err := ValidatePlaceholders(object, refs, field.NewPath("template"))
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("template.data.script"))
+		_, err = resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: refsPath})
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("data.script"))
+
+		By("keeping the escaped literal value")
+		object = newTemplate(map[string]interface{}{"script": "echo $${HOME}", "user": "${user}"})
+		Expect(ValidatePlaceholders(object, refs, field.NewPath("template"))).To(Succeed())
+		_, err = resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: refsPath})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved["data"]).To(Equal(map[string]interface{}{"script": "echo ${HOME}", "user": "admin"}))
+	})
+
+	It("should report undeclared and unresolved references", func() {
+		object := newTemplate(map[string]interface{}{"url": "${password}"})
+		resolved := map[string]interface{}{}
+		_, err := resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: refsPath})
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("data.url"))
+
+		refs["password"] = map[string]interface{}{"kind": "Secret", "name": "db", "path": "{.data.password}"}
+		_, err = resolver.ResolveObjectWithOptions(ctx, object, &resolved, ResolveOptions{Refs: refs, RefsPath: refsPath})
+		Expect(IsObjectNotFound(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("refs.password"))
+	})
+
+	It("should validate the named references and the placeholders", func() {
+		Expect(ValidateRefs(refs, refsPath)).To(Succeed())
+		refs["bad name"] = map[string]interface{}{"kind": "ConfigMap", "name": "db", "path": "{.data.user}"}
+		refs["noPath"] = map[string]interface{}{"kind": "ConfigMap", "name": "db"}
+		err := ValidateRefs(refs, refsPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("refs[bad name]"))
+		Expect(err.Error()).To(ContainSubstring("refs.noPath.path"))
+
+		object := newTemplate(map[string]interface{}{"url": "${user}:${password}", "escaped": "$${password}"})
+		err = ValidatePlaceholders(object, refs, field.NewPath("template"))
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("template.data.url"))
+		Expect(err.Error()).NotTo(ContainSubstring("template.data.escaped"))
+		Expect(ValidatePlaceholders(object, nil, field.NewPath("template"))).To(Succeed())
+	})
+})
@@ -0,0 +1,157 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// waitFor - the element of an object reference that defines when its input object is ready
+	waitFor = "waitFor"
+
+	// the fields of the waitFor element
+	waitCondition = "condition"
+	waitStatus    = "status"
+	waitValue     = "value"
+
+	// defaultConditionStatus - the status of the waited condition, if it is not defined
+	defaultConditionStatus = "True"
+)
+
+// validateWaitFor returns IllFormedRef errors for the invalid fields of the waitFor element of an object reference.
+// The element defines either a condition type with an optional status, or a path with the expected value.
+func validateWaitFor(value interface{}, fldPath *field.Path) []error {
+	val, ok := value.(map[string]interface{})
+	if !ok {
+		return []error{illFormed(fldPath, "'waitFor' is not an object")}
+	}
+	var errs []error
+	condition, conditionOK := val[waitCondition]
+	waitPath, pathOK := val[path]
+	if conditionOK && pathOK {
+		errs = append(errs, illFormed(fldPath, "both 'condition' and 'path' cannot be defined at the same time"))
+	} else if !conditionOK && !pathOK {
+		errs = append(errs, illFormed(fldPath, "neither 'condition' nor 'path' are defined (one expected)"))
+	}
+	if conditionOK {
+		if str, ok := condition.(string); !ok || len(str) == 0 {
+			errs = append(errs, illFormed(fldPath.Child(waitCondition), "'condition' is not a condition type"))
+		}
+		if _, ok := val[waitValue]; ok {
+			errs = append(errs, illFormed(fldPath.Child(waitValue), "'value' can be defined only with 'path'"))
+		}
+	}
+	if status, ok := val[waitStatus]; ok {
+		if str, isString := status.(string); !isString || len(str) == 0 {
+			errs = append(errs, illFormed(fldPath.Child(waitStatus), "'status' is not a condition status"))
+		} else if pathOK {
+			errs = append(errs, illFormed(fldPath.Child(waitStatus), "'status' can be defined only with 'condition'"))
+		}
+	}
+	if pathOK {
+		if str, ok := waitPath.(string); !ok || !strings.HasPrefix(str, "{.") {
+			errs = append(errs, illFormed(fldPath.Child(path), "'path' must start with '{.'"))
+		} else if _, err := parsePath(str); err != nil {
+			// the path is parsed as valueReady parses it
+			errs = append(errs, illFormed(fldPath.Child(path), fmt.Sprintf("'path' is not a valid jsonpath: %s", err.Error())))
+		}
+		if _, ok := val[waitValue]; !ok {
+			errs = append(errs, illFormed(fldPath.Child(waitValue), "'value' is not defined"))
+		}
+	}
+	return errs
+}
+
+// checkReady returns a NotReady error if an input object does not satisfy the waitFor element of the object reference.
+// The selected object is checked, or all input objects if the values of all of them are aggregated.
+func (in *inputObjects) checkReady(val map[string]interface{}, selected *unstructured.Unstructured) error {
+	if selected != nil {
+		return objectReady(val, selected)
+	}
+	for i := range in.items {
+		if err := objectReady(val, &in.items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectReady returns a NotReady error if the input object does not satisfy the waitFor element of the object reference
+func objectReady(val map[string]interface{}, unstrObj *unstructured.Unstructured) error {
+	wait, ok := val[waitFor].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var ready bool
+	var message string
+	var err error
+	if conditionType, ok := wait[waitCondition].(string); ok {
+		ready, message = conditionReady(wait, unstrObj, conditionType)
+	} else {
+		ready, message, err = valueReady(wait, unstrObj)
+	}
+	if err != nil || ready {
+		return err
+	}
+	return &ResolveError{Reason: ReasonNotReady, GroupVersionKind: unstrObj.GroupVersionKind(), Namespace: unstrObj.GetNamespace(),
+		Name: unstrObj.GetName(), Message: fmt.Sprintf("%s %s/%s is not ready: %s", unstrObj.GetKind(), unstrObj.GetNamespace(), unstrObj.GetName(), message)}
+}
+
+// conditionReady checks that the condition of the given type in status.conditions has the waited status.
+// A condition observed for an older generation of the object is not taken into account.
+func conditionReady(wait map[string]interface{}, unstrObj *unstructured.Unstructured, conditionType string) (bool, string) {
+	waitedStatus, ok := wait[waitStatus].(string)
+	if !ok {
+		waitedStatus = defaultConditionStatus
+	}
+	conditions, _, _ := unstructured.NestedSlice(unstrObj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+		if generation, ok, _ := unstructured.NestedInt64(condition, "observedGeneration"); ok && generation < unstrObj.GetGeneration() {
+			return false, fmt.Sprintf("condition %s is observed for generation %d, not %d", conditionType, generation, unstrObj.GetGeneration())
+		}
+		if condition["status"] != waitedStatus {
+			return false, fmt.Sprintf("condition %s is %v, not %s", conditionType, condition["status"], waitedStatus)
+		}
+		return true, ""
+	}
+	return false, fmt.Sprintf("condition %s is not reported", conditionType)
+}
+
+// valueReady checks that a value matched by the path equals the waited value, the values are compared in their string forms
+func valueReady(wait map[string]interface{}, unstrObj *unstructured.Unstructured) (bool, string, error) {
+	waitPath, _ := wait[path].(string)
+	waitedValue := fmt.Sprintf("%v", wait[waitValue])
+	matches, err := resolveValue2(map[string]interface{}{multi: true}, *unstrObj, waitPath, nil)
+	if IsValueNotFound(err) {
+		return false, fmt.Sprintf("%s does not match any value", waitPath), nil
+	} else if err != nil {
+		return false, "", err
+	}
+	for _, match := range matches.([]interface{}) {
+		if fmt.Sprintf("%v", match) == waitedValue {
+			return true, "", nil
+		}
+	}
+	return false, fmt.Sprintf("%s is not %s", waitPath, waitedValue), nil
+}
@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Readiness", func() {
+	var (
+		ctx      context.Context
+		resolver KubernetesResourceResolver
+	)
+
+	newPod := func(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "db"}},
+			Status: corev1.PodStatus{
+				Phase:      phase,
+				PodIP:      "10.0.0." + name[len(name)-1:],
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+	podRef := func(name string, wait interface{}) map[string]interface{} {
+		return map[string]interface{}{"kind": "Pod", "name": name, "path": "{.status.podIP}", "waitFor": wait}
+	}
+	resolve := func(ref map[string]interface{}) (interface{}, error) {
+		resolved := map[string]interface{}{}
+		err := resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)
+		if err != nil {
+			return nil, err
+		}
+		return resolved["data"].(map[string]interface{})["value"], nil
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			newPod("db-1", corev1.PodRunning, corev1.ConditionTrue),
+			newPod("db-2", corev1.PodPending, corev1.ConditionFalse),
+		).Build()
+		resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+	})
+
+	DescribeTable("should resolve the value of a ready input object",
+		func(wait map[string]interface{}) {
+			Expect(resolve(podRef("db-1", wait))).To(Equal("10.0.0.1"))
+		},
+		Entry("condition", map[string]interface{}{"condition": "Ready"}),
+		Entry("condition with status", map[string]interface{}{"condition": "Ready", "status": "True"}),
+		Entry("path", map[string]interface{}{"path": "{.status.phase}", "value": "Running"}),
+		Entry("path with several matches", map[string]interface{}{"path": "{.status.conditions[*].status}", "value": "True"}),
+	)
+
+	DescribeTable("should report input objects that are not ready",
+		func(wait map[string]interface{}, message string) {
+			_, err := resolve(podRef("db-2", wait))
+			Expect(IsNotReady(err)).To(BeTrue())
+			Expect(IsRefNotFound(err)).To(BeFalse())
+			Expect(err.Error()).To(ContainSubstring(message))
+			Expect(err.Error()).To(ContainSubstring("data.value.getValueFrom"))
+		},
+		Entry("condition", map[string]interface{}{"condition": "Ready"}, "condition Ready is False, not True"),
+		Entry("missing condition", map[string]interface{}{"condition": "Initialized"}, "condition Initialized is not reported"),
+		Entry("path", map[string]interface{}{"path": "{.status.phase}", "value": "Running"}, "{.status.phase} is not Running"),
+		Entry("missing path", map[string]interface{}{"path": "{.status.hostIP}", "value": "10.0.0.1"}, "{.status.hostIP} does not match any value"),
+	)
+
+	It("should not use the default value of an input object that is not ready", func() {
+		ref := podRef("db-2", map[string]interface{}{"condition": "Ready"})
+		ref[defaultValue] = "127.0.0.1"
+		_, err := resolve(ref)
+		Expect(IsNotReady(err)).To(BeTrue())
+	})
+
+	It("should wait for all aggregated input objects", func() {
+		ref := map[string]interface{}{"kind": "Pod", "labels": map[string]interface{}{"app": "db"}, "path": "{.status.podIP}",
+			"aggregate": AggregateList, "waitFor": map[string]interface{}{"condition": "Ready"}}
+		_, err := resolve(ref)
+		Expect(IsNotReady(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("db-2"))
+	})
+
+	It("should validate the waitFor element", func() {
+		fldPath := field.NewPath(GetValueFrom)
+		Expect(ValidateReference(podRef("db-1", map[string]interface{}{"condition": "Ready", "status": "False"}), fldPath)).To(Succeed())
+
+		err := ValidateReference(podRef("db-1", map[string]interface{}{}), fldPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("neither 'condition' nor 'path'")))
+
+		err = ValidateReference(podRef("db-1", map[string]interface{}{"condition": "Ready", "path": "{.status.phase}", "value": "Running"}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("both 'condition' and 'path'")))
+
+		err = ValidateReference(podRef("db-1", map[string]interface{}{"path": "{.status.phase}"}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("getValueFrom.waitFor.value")))
+
+		err = ValidateReference(podRef("db-1", map[string]interface{}{"path": "status.phase", "value": "Running"}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("getValueFrom.waitFor.path")))
+
+		err = ValidateReference(podRef("db-1", map[string]interface{}{"path": "{.status.conditions[?(@.type==)]}", "value": "True"}), fldPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("getValueFrom.waitFor.path: GetValueFrom is not well-formed, 'path' is not a valid jsonpath")))
+
+		err = ValidateReference(podRef("db-1", "Ready"), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("'waitFor' is not an object")))
+	})
+})
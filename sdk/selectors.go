@@ -0,0 +1,183 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// objectSelector selects the input objects of an object reference by their labels and fields
+type objectSelector struct {
+	// matchLabels are the labels of the labels element, they are reported as the Labels of the input objects
+	matchLabels map[string]string
+	// labelSelector is the selector of the labelSelector element
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+}
+
+// referenceSelector returns the selector of an object reference, or nil if the input object is read by name
+func referenceSelector(val map[string]interface{}) (*objectSelector, error) {
+	intLabels, hasLabels := val[Labels].(map[string]interface{})
+	rawLabelSelector, hasLabelSelector := val[LabelSelector]
+	rawFieldSelector, hasFieldSelector := val[FieldSelector]
+	if !hasLabels && !hasLabelSelector && !hasFieldSelector {
+		return nil, nil
+	}
+	sel := &objectSelector{labelSelector: labels.Everything(), fieldSelector: fields.Everything()}
+	if hasLabels {
+		sel.matchLabels = make(map[string]string)
+		for key, value := range intLabels {
+			sel.matchLabels[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	if hasLabelSelector {
+		labelSelector, err := parseLabelSelector(rawLabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		sel.labelSelector = labelSelector
+	}
+	if hasFieldSelector {
+		fieldSelector, err := parseFieldSelector(rawFieldSelector)
+		if err != nil {
+			return nil, err
+		}
+		sel.fieldSelector = fieldSelector
+	}
+	return sel, nil
+}
+
+// parseLabelSelector parses a label selector with matchLabels and matchExpressions
+func parseLabelSelector(value interface{}) (labels.Selector, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var labelSelector metav1.LabelSelector
+	if err := json.Unmarshal(data, &labelSelector); err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(&labelSelector)
+}
+
+// parseFieldSelector parses a field selector, e.g. status.phase=Running
+func parseFieldSelector(value interface{}) (fields.Selector, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a string", FieldSelector)
+	}
+	return fields.ParseSelector(str)
+}
+
+// customResourceSelectableFields - the fields that the API server supports in the field selectors of custom resources
+var customResourceSelectableFields = []string{"metadata.name", "metadata.namespace"}
+
+// isCustomResource returns true if the API version belongs to a custom resource, rather than to a built-in kind of
+// Kubernetes, whose groups are either not qualified, e.g. apps, or end with .k8s.io
+func isCustomResource(apiVersion string) bool {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	return err == nil && strings.Contains(gv.Group, ".") && !strings.HasSuffix(gv.Group, ".k8s.io")
+}
+
+// validateCustomFieldSelector returns IllFormedRef errors for the fields of a field selector of custom resources that
+// the API server does not support. Lists of unstructured objects are not cached, so field selectors are evaluated by
+// the API server, that would reject the other fields when the reference is resolved.
+func validateCustomFieldSelector(fieldSelector fields.Selector, fldPath *field.Path) []error {
+	var errs []error
+	for _, requirement := range fieldSelector.Requirements() {
+		supported := false
+		for _, selectable := range customResourceSelectableFields {
+			supported = supported || requirement.Field == selectable
+		}
+		if !supported {
+			errs = append(errs, illFormed(fldPath, fmt.Sprintf("field %q is not supported by the field selectors of custom resources, only %s are",
+				requirement.Field, strings.Join(customResourceSelectableFields, " and "))))
+		}
+	}
+	return errs
+}
+
+// selector returns the label selector of the input objects, it combines the labels and the labelSelector elements
+func (s *objectSelector) selector() labels.Selector {
+	if s.matchLabels == nil {
+		return s.labelSelector
+	}
+	return labels.SelectorFromSet(s.matchLabels)
+}
+
+// String returns the selectors, it is used in the keys of the cached input objects
+func (s *objectSelector) String() string {
+	return fmt.Sprintf("%s/%s", s.selector().String(), s.fieldSelector.String())
+}
+
+// input returns the input object that records the selected objects
+func (s *objectSelector) input(gvk schema.GroupVersionKind, namespace string) InputObject {
+	input := InputObject{GroupVersionKind: gvk, Namespace: namespace, Labels: s.matchLabels}
+	if s.matchLabels == nil {
+		input.LabelSelector = s.labelSelector.String()
+	}
+	if !s.fieldSelector.Empty() {
+		input.FieldSelector = s.fieldSelector.String()
+	}
+	return input
+}
+
+// SelectorMatches returns true if the object matches the label selector and the field selector, which are given
+// in their string forms, e.g. "app in (db,web)" and "status.phase=Running". Empty selectors match all objects.
+func SelectorMatches(obj *unstructured.Unstructured, labelSelector, fieldSelector string) (bool, error) {
+	lblSelector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return false, err
+	}
+	fldSelector, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return false, err
+	}
+	return lblSelector.Matches(labels.Set(obj.GetLabels())) && fieldsMatch(obj, fldSelector), nil
+}
+
+// fieldsMatch returns true if the fields of the object match the field selector. The API server supports only some
+// fields of every kind in field selectors, the selected objects are checked again, so caches and fake clients,
+// which may ignore field selectors, select the same objects.
+func fieldsMatch(obj *unstructured.Unstructured, fieldSelector fields.Selector) bool {
+	for _, requirement := range fieldSelector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(requirement.Field, ".")...)
+		str := ""
+		if found && err == nil {
+			str = fmt.Sprintf("%v", value)
+		}
+		switch requirement.Operator {
+		case selection.Equals, selection.DoubleEquals:
+			if str != requirement.Value {
+				return false
+			}
+		case selection.NotEquals:
+			if str == requirement.Value {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Selectors", func() {
+	var (
+		ctx      context.Context
+		resolver KubernetesResourceResolver
+	)
+
+	hostsRef := func(selectors map[string]interface{}) map[string]interface{} {
+		ref := map[string]interface{}{"kind": "ConfigMap", "path": "{.data.host}", "aggregate": AggregateList}
+		for k, v := range selectors {
+			ref[k] = v
+		}
+		return ref
+	}
+	expressions := func(expressions ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"matchExpressions": expressions}
+	}
+	requirement := func(key, operator string, values ...interface{}) map[string]interface{} {
+		req := map[string]interface{}{"key": key, "operator": operator}
+		if len(values) > 0 {
+			req["values"] = values
+		}
+		return req
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			newConfigMap("db", map[string]string{"app": "db", "tier": "backend"}, map[string]string{"host": "db.example.com"}),
+			newConfigMap("web", map[string]string{"app": "web", "tier": "frontend"}, map[string]string{"host": "web.example.com"}),
+			newConfigMap("cache", map[string]string{"app": "cache"}, map[string]string{"host": "cache.example.com"}),
+		).Build()
+		resolver = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+	})
+
+	DescribeTable("should select the input objects",
+		func(selectors map[string]interface{}, expected []interface{}) {
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(hostsRef(selectors))}), &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"value": expected}))
+		},
+		Entry("In", map[string]interface{}{LabelSelector: expressions(requirement("app", "In", "db", "web"))},
+			[]interface{}{"db.example.com", "web.example.com"}),
+		Entry("NotIn", map[string]interface{}{LabelSelector: expressions(requirement("app", "NotIn", "db"))},
+			[]interface{}{"cache.example.com", "web.example.com"}),
+		Entry("Exists", map[string]interface{}{LabelSelector: expressions(requirement("tier", "Exists"))},
+			[]interface{}{"db.example.com", "web.example.com"}),
+		Entry("DoesNotExist", map[string]interface{}{LabelSelector: expressions(requirement("tier", "DoesNotExist"))},
+			[]interface{}{"cache.example.com"}),
+		Entry("matchLabels and matchExpressions", map[string]interface{}{LabelSelector: map[string]interface{}{
+			"matchLabels":      map[string]interface{}{"tier": "backend"},
+			"matchExpressions": []interface{}{requirement("app", "In", "db", "web")},
+		}}, []interface{}{"db.example.com"}),
+		Entry("fields", map[string]interface{}{FieldSelector: "metadata.name!=db"},
+			[]interface{}{"cache.example.com", "web.example.com"}),
+		Entry("labels and fields", map[string]interface{}{Labels: map[string]interface{}{"tier": "frontend"}, FieldSelector: "metadata.name=web"},
+			[]interface{}{"web.example.com"}),
+	)
+
+	It("should report the selectors of the input objects", func() {
+		ref := hostsRef(map[string]interface{}{LabelSelector: expressions(requirement("app", "In", "db", "web")), FieldSelector: "metadata.name=db"})
+		resolved := map[string]interface{}{}
+		inputs, err := resolver.ResolveObjectDependencies(ctx, newTemplate(map[string]interface{}{"value": getValueFrom(ref)}), &resolved)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inputs).To(ContainElement(InputObject{
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Namespace:        "default",
+			LabelSelector:    "app in (db,web)",
+			FieldSelector:    "metadata.name=db",
+		}))
+	})
+
+	It("should validate the selectors", func() {
+		fldPath := field.NewPath(GetValueFrom)
+		Expect(ValidateReference(hostsRef(map[string]interface{}{LabelSelector: expressions(requirement("app", "Exists"))}), fldPath)).To(Succeed())
+		Expect(ValidateReference(hostsRef(map[string]interface{}{FieldSelector: "metadata.name=db"}), fldPath)).To(Succeed())
+
+		err := ValidateReference(hostsRef(map[string]interface{}{LabelSelector: expressions(requirement("app", "Between", "a"))}), fldPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("getValueFrom.labelSelector"))
+
+		err = ValidateReference(hostsRef(map[string]interface{}{LabelSelector: expressions(requirement("app", "In"))}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("getValueFrom.labelSelector")))
+
+		err = ValidateReference(hostsRef(map[string]interface{}{FieldSelector: "metadata.name"}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("getValueFrom.fieldSelector")))
+
+		err = ValidateReference(hostsRef(map[string]interface{}{Labels: map[string]interface{}{"app": "db"}, LabelSelector: expressions()}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("both 'labels' and 'labelSelector'")))
+
+		err = ValidateReference(hostsRef(map[string]interface{}{Name: "db", FieldSelector: "metadata.name=db"}), fldPath)
+		Expect(err).To(MatchError(ContainSubstring("'name' cannot be defined with 'labelSelector' or 'fieldSelector'")))
+
+		By("rejecting the fields that custom resources do not support in field selectors")
+		ref := hostsRef(map[string]interface{}{FieldSelector: "metadata.namespace=default,status.state=Online"})
+		Expect(ValidateReference(ref, fldPath)).To(Succeed())
+		ref["apiVersion"] = "v1"
+		Expect(ValidateReference(ref, fldPath)).To(Succeed())
+		ref["apiVersion"] = "ibmcloud.ibm.com/v1alpha1"
+		err = ValidateReference(ref, fldPath)
+		Expect(IsIllFormedRef(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`field "status.state" is not supported by the field selectors of custom resources`))
+		Expect(err.Error()).NotTo(ContainSubstring(`"metadata.namespace" is not supported`))
+	})
+
+	It("should match objects against the selectors in their string forms", func() {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "db", "labels": map[string]interface{}{"app": "db"}},
+			"status":   map[string]interface{}{"phase": "Running"},
+		}}
+		Expect(SelectorMatches(obj, "", "")).To(BeTrue())
+		Expect(SelectorMatches(obj, "app in (db,web)", "status.phase=Running")).To(BeTrue())
+		Expect(SelectorMatches(obj, "app notin (db)", "")).To(BeFalse())
+		Expect(SelectorMatches(obj, "", "status.phase!=Running")).To(BeFalse())
+		_, err := SelectorMatches(obj, "app in (", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -19,8 +19,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -53,8 +55,61 @@ const (
 
 	// ToString - name of the transformer, that transforms any object to its native string representation
 	ToString = "ToString"
+
+	// Split - name of the transformer, that splits a string by a separator
+	Split = "Split"
+
+	// Join - name of the transformer, that joins array's values by a separator
+	Join = "Join"
+
+	// Index - name of the transformer, that returns the array's value at an index
+	Index = "Index"
+
+	// Prefix - name of the transformer, that prepends a prefix to a value
+	Prefix = "Prefix"
+
+	// Suffix - name of the transformer, that appends a suffix to a value
+	Suffix = "Suffix"
+
+	// DefaultIfEmpty - name of the transformer, that replaces an empty value by a default one
+	DefaultIfEmpty = "DefaultIfEmpty"
 )
 
+// the types of the values that the transformers accept and return
+const (
+	anyValue     = "any"
+	stringValue  = "string"
+	numberValue  = "number"
+	booleanValue = "boolean"
+	arrayValue   = "array"
+)
+
+// transformerSignature - the types of the input and output values of a transformer
+type transformerSignature struct {
+	in, out string
+}
+
+// builtinSignatures - the signatures of the built-in transformers, the transformers registered by users accept and
+// return values of any type
+var builtinSignatures = map[string]transformerSignature{
+	ToString:        {anyValue, stringValue},
+	Base64ToString:  {stringValue, stringValue},
+	StringToBase64:  {stringValue, stringValue},
+	StringToInt:     {stringValue, numberValue},
+	StringToInt32:   {stringValue, numberValue},
+	StringToFloat:   {stringValue, numberValue},
+	StringToBool:    {stringValue, booleanValue},
+	ArrayToCSString: {anyValue, stringValue},
+	JSONToObject:    {stringValue, anyValue},
+	ObjectToJSON:    {anyValue, stringValue},
+	Split:           {stringValue, arrayValue},
+	Join:            {arrayValue, stringValue},
+	Index:           {arrayValue, anyValue},
+	Prefix:          {anyValue, stringValue},
+	Suffix:          {anyValue, stringValue},
+	DefaultIfEmpty:  {anyValue, anyValue},
+}
+
 // Transformer - the base transformer function
 type Transformer func(interface{}) (interface{}, error)
 
@@ -71,47 +126,263 @@ func CompoundTransformer(value interface{}, transformers ...Transformer) (interf
 	return tempValue, nil
 }
 
-// CompoundTransformerNames returns names of the given transformers
+// CompoundTransformerNames applies the transformers of the given names, from the DefaultTransformerRegistry, one after another
 func CompoundTransformerNames(value interface{}, transNames ...string) (interface{}, error) {
-	tempValue := value
-	for _, trName := range transNames {
-		tr, err := string2Transformer(trName)
-		if err != nil {
+	return DefaultTransformerRegistry.Transform(value, transNames...)
+}
+
+// TransformerFactory returns a transformer for the given arguments, it fails if the number or the format of the arguments is wrong
+type TransformerFactory func(args ...string) (Transformer, error)
+
+// TransformerRegistry maps transformer names to transformer factories. It is safe for concurrent use.
+type TransformerRegistry struct {
+	lock       sync.RWMutex
+	factories  map[string]TransformerFactory
+	signatures map[string]transformerSignature
+}
+
+// DefaultTransformerRegistry holds the built-in transformers, it is used by resolvers without their own registry
+var DefaultTransformerRegistry = NewDefaultTransformerRegistry()
+
+// NewTransformerRegistry returns an empty registry
+func NewTransformerRegistry() *TransformerRegistry {
+	return &TransformerRegistry{factories: make(map[string]TransformerFactory), signatures: make(map[string]transformerSignature)}
+}
+
+// NewDefaultTransformerRegistry returns a registry with the built-in transformers, more transformers can be registered in it
+func NewDefaultTransformerRegistry() *TransformerRegistry {
+	r := NewTransformerRegistry()
+	for name, tr := range map[string]Transformer{
+		ToString:        ToStringTransformer,
+		Base64ToString:  Base642StringTransformer,
+		StringToBase64:  String2Base64Transformer,
+		StringToInt:     String2IntTransformer,
+		StringToInt32:   String2Int32Transformer,
+		StringToFloat:   String2FloatTransformer,
+		StringToBool:    String2BoolTransformer,
+		ArrayToCSString: Array2CSStringTransformer,
+		JSONToObject:    JSONToObjectTransformer,
+		ObjectToJSON:    ObjectToJSONTransformer,
+	} {
+		r.factories[name] = noArgsFactory(name, tr)
+	}
+	for name, factory := range map[string]TransformerFactory{
+		Split:          SplitTransformerFactory,
+		Join:           JoinTransformerFactory,
+		Index:          IndexTransformerFactory,
+		Prefix:         PrefixTransformerFactory,
+		Suffix:         SuffixTransformerFactory,
+		DefaultIfEmpty: DefaultIfEmptyTransformerFactory,
+	} {
+		r.factories[name] = factory
+	}
+	for name, signature := range builtinSignatures {
+		r.signatures[name] = signature
+	}
+	return r
+}
+
+// noArgsFactory returns a factory of a transformer without arguments
+func noArgsFactory(name string, transformer Transformer) TransformerFactory {
+	return func(args ...string) (Transformer, error) {
+		if err := checkArgs(name, args, 0); err != nil {
 			return nil, err
 		}
-		tempValue, err = tr(tempValue)
+		return transformer, nil
+	}
+}
+
+// checkArgs checks the number of the transformer arguments
+func checkArgs(name string, args []string, expected int) error {
+	if len(args) != expected {
+		return fmt.Errorf("Transformer %q expects %d argument(s), %d given", name, expected, len(args))
+	}
+	return nil
+}
+
+// Register adds a transformer without arguments with the given name, a registered name cannot be reused
+func (r *TransformerRegistry) Register(name string, transformer Transformer) error {
+	if transformer == nil {
+		return fmt.Errorf("Transformer name and function must be defined")
+	}
+	return r.RegisterFactory(name, noArgsFactory(name, transformer))
+}
+
+// RegisterFactory adds a parameterized transformer with the given name, a registered name cannot be reused
+func (r *TransformerRegistry) RegisterFactory(name string, factory TransformerFactory) error {
+	if len(name) == 0 || factory == nil {
+		return fmt.Errorf("Transformer name and function must be defined")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("Transformer %q is already registered", name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Get returns the transformer of the given name, that does not take arguments
+func (r *TransformerRegistry) Get(name string) (Transformer, error) {
+	return r.New(FormatTransformer{Name: name})
+}
+
+// New returns the transformer of the given name, with the given arguments
+func (r *TransformerRegistry) New(transformer FormatTransformer) (Transformer, error) {
+	r.lock.RLock()
+	factory, ok := r.factories[transformer.Name]
+	r.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Wrong transformer name %q", transformer.Name)
+	}
+	return factory(transformer.Args...)
+}
+
+// checkChain returns an error if the next transformer does not accept the type of the values returned by the previous one,
+// e.g. StringToInt after StringToInt. Transformers without signatures accept and return values of any type.
+func (r *TransformerRegistry) checkChain(previous, next string) error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	out, in := r.signatures[previous].out, r.signatures[next].in
+	if len(out) == 0 || len(in) == 0 || out == anyValue || in == anyValue || out == in {
+		return nil
+	}
+	return fmt.Errorf("Transformer %q expects a %s value, but transformer %q returns a %s value", next, in, previous, out)
+}
+
+// Names returns the sorted names of the registered transformers
+func (r *TransformerRegistry) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Transform applies the transformers of the given names one after another
+func (r *TransformerRegistry) Transform(value interface{}, transNames ...string) (interface{}, error) {
+	transformers := make([]FormatTransformer, 0, len(transNames))
+	for _, trName := range transNames {
+		transformers = append(transformers, FormatTransformer{Name: trName})
+	}
+	return r.Apply(value, transformers...)
+}
+
+// Apply applies the given transformers with their arguments one after another
+func (r *TransformerRegistry) Apply(value interface{}, formatTransformers ...FormatTransformer) (interface{}, error) {
+	transformers := make([]Transformer, 0, len(formatTransformers))
+	for _, ft := range formatTransformers {
+		tr, err := r.New(ft)
 		if err != nil {
 			return nil, err
 		}
+		transformers = append(transformers, tr)
 	}
-	return tempValue, nil
+	return CompoundTransformer(value, transformers...)
 }
 
-func string2Transformer(transformerName string) (Transformer, error) {
-	switch transformerName {
-	case ToString:
-		return ToStringTransformer, nil
-	case Base64ToString:
-		return Base642StringTransformer, nil
-	case StringToBase64:
-		return String2Base64Transformer, nil
-	case StringToInt:
-		return String2IntTransformer, nil
-	case StringToInt32:
-		return String2Int32Transformer, nil
-	case StringToFloat:
-		return String2FloatTransformer, nil
-	case StringToBool:
-		return String2BoolTransformer, nil
-	case ArrayToCSString:
-		return Array2CSStringTransformer, nil
-	case JSONToObject:
-		return JSONToObjectTransformer, nil
-	case ObjectToJSON:
-		return ObjectToJSONTransformer, nil
-	default:
-		return nil, fmt.Errorf("Wrong transformer name %q", transformerName)
+// SplitTransformerFactory returns a transformer, that splits a string by the separator given as the argument
+func SplitTransformerFactory(args ...string) (Transformer, error) {
+	if err := checkArgs(Split, args, 1); err != nil {
+		return nil, err
 	}
+	return func(value interface{}) (interface{}, error) {
+		strValue, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("The given %v has type %T, and it is not a string", value, value)
+		}
+		parts := strings.Split(strValue, args[0])
+		retVal := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			retVal = append(retVal, part)
+		}
+		return retVal, nil
+	}, nil
+}
+
+// JoinTransformerFactory returns a transformer, that joins array's values by the separator given as the argument
+func JoinTransformerFactory(args ...string) (Transformer, error) {
+	if err := checkArgs(Join, args, 1); err != nil {
+		return nil, err
+	}
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return "", nil
+		}
+		switch reflect.TypeOf(value).Kind() {
+		case reflect.Slice, reflect.Array:
+			s := reflect.ValueOf(value)
+			parts := make([]string, 0, s.Len())
+			for i := 0; i < s.Len(); i++ {
+				parts = append(parts, fmt.Sprintf("%v", s.Index(i)))
+			}
+			return strings.Join(parts, args[0]), nil
+		default:
+			return nil, fmt.Errorf("The given %v has type %T, and it is not an array", value, value)
+		}
+	}, nil
+}
+
+// IndexTransformerFactory returns a transformer, that returns the array's value at the index given as the argument
+func IndexTransformerFactory(args ...string) (Transformer, error) {
+	if err := checkArgs(Index, args, 1); err != nil {
+		return nil, err
+	}
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 {
+		return nil, fmt.Errorf("Transformer %q expects a non-negative integer argument, %q given", Index, args[0])
+	}
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, fmt.Errorf("The given %v has type %T, and it is not an array", value, value)
+		}
+		switch reflect.TypeOf(value).Kind() {
+		case reflect.Slice, reflect.Array:
+			s := reflect.ValueOf(value)
+			if index >= s.Len() {
+				return nil, fmt.Errorf("Index %d is out of range of the given array of length %d", index, s.Len())
+			}
+			return s.Index(index).Interface(), nil
+		default:
+			return nil, fmt.Errorf("The given %v has type %T, and it is not an array", value, value)
+		}
+	}, nil
+}
+
+// PrefixTransformerFactory returns a transformer, that prepends the argument to the string representation of a value
+func PrefixTransformerFactory(args ...string) (Transformer, error) {
+	if err := checkArgs(Prefix, args, 1); err != nil {
+		return nil, err
+	}
+	return func(value interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s%v", args[0], value), nil
+	}, nil
+}
+
+// SuffixTransformerFactory returns a transformer, that appends the argument to the string representation of a value
+func SuffixTransformerFactory(args ...string) (Transformer, error) {
+	if err := checkArgs(Suffix, args, 1); err != nil {
+		return nil, err
+	}
+	return func(value interface{}) (interface{}, error) {
+		return fmt.Sprintf("%v%s", value, args[0]), nil
+	}, nil
+}
+
+// DefaultIfEmptyTransformerFactory returns a transformer, that replaces an empty string or a null value by the argument
+func DefaultIfEmptyTransformerFactory(args ...string) (Transformer, error) {
+	if err := checkArgs(DefaultIfEmpty, args, 1); err != nil {
+		return nil, err
+	}
+	return func(value interface{}) (interface{}, error) {
+		if value == nil || value == "" {
+			return args[0], nil
+		}
+		return value, nil
+	}, nil
 }
 
 // Array2CSStringTransformer ...
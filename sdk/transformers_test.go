@@ -15,9 +15,17 @@ limitations under the License.
 package sdk
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 type Planet struct {
@@ -63,4 +71,130 @@ var _ = Describe("./pkg/controller/composable/trnsformers", func() {
 		Entry("single string", "test", "test"),
 		Entry("single int", 12, "12"),
 		Entry("single boolean", true, "true"))
+
+	Context("TransformerRegistry", func() {
+		toUpper := func(value interface{}) (interface{}, error) {
+			return strings.ToUpper(fmt.Sprintf("%v", value)), nil
+		}
+
+		It("should hold the built-in transformers by default", func() {
+			registry := NewDefaultTransformerRegistry()
+			Expect(registry.Names()).To(ContainElements(ToString, StringToInt, Base64ToString, ObjectToJSON))
+			Expect(registry.Transform("12", StringToInt, ToString)).To(Equal("12"))
+		})
+
+		It("should register user transformers", func() {
+			registry := NewDefaultTransformerRegistry()
+			Expect(registry.Register("ToUpper", toUpper)).To(Succeed())
+			Expect(registry.Transform(true, "ToUpper")).To(Equal("TRUE"))
+			Expect(registry.Register("ToUpper", toUpper)).NotTo(Succeed())
+			Expect(registry.Register(ToString, toUpper)).NotTo(Succeed())
+			Expect(registry.Register("", toUpper)).NotTo(Succeed())
+
+			By("keeping the default registry unchanged")
+			_, err := DefaultTransformerRegistry.Get("ToUpper")
+			Expect(err).To(MatchError(ContainSubstring("Wrong transformer name \"ToUpper\"")))
+		})
+
+		It("should be used by the resolver", func() {
+			registry := NewDefaultTransformerRegistry()
+			Expect(registry.Register("ToUpper", toUpper)).To(Succeed())
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				newConfigMap("input", nil, map[string]string{"host": "example.com"}),
+			).Build()
+			resolver := KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources(), Transformers: registry}
+			ref := map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.host}", Transformers: []interface{}{"ToUpper"}}
+
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(context.Background(), newTemplate(map[string]interface{}{"host": getValueFrom(ref)}), &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"host": "EXAMPLE.COM"}))
+			Expect(resolver.ValidateReference(ref, field.NewPath(GetValueFrom))).To(Succeed())
+
+			By("rejecting unknown transformers")
+			err := ValidateReference(ref, field.NewPath(GetValueFrom))
+			Expect(IsIllFormedRef(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("getValueFrom.format-transformers[0]"))
+			err = KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}.ResolveObject(context.Background(),
+				newTemplate(map[string]interface{}{"host": getValueFrom(ref)}), &resolved)
+			Expect(IsIllFormedRef(err)).To(BeTrue())
+		})
+	})
+
+	Context("Transformers with arguments", func() {
+		registry := NewDefaultTransformerRegistry()
+
+		It("should apply the parameterized transformers", func() {
+			Expect(registry.Apply("a;b;c", FormatTransformer{Name: Split, Args: []string{";"}})).To(Equal([]interface{}{"a", "b", "c"}))
+			Expect(registry.Apply("a;b;c", FormatTransformer{Name: Split, Args: []string{";"}}, FormatTransformer{Name: Index, Args: []string{"1"}})).To(Equal("b"))
+			Expect(registry.Apply([]interface{}{"a", 1}, FormatTransformer{Name: Join, Args: []string{","}})).To(Equal("a,1"))
+			Expect(registry.Apply("host", FormatTransformer{Name: Prefix, Args: []string{"http://"}}, FormatTransformer{Name: Suffix, Args: []string{":80"}})).To(Equal("http://host:80"))
+			Expect(registry.Apply("", FormatTransformer{Name: DefaultIfEmpty, Args: []string{"none"}})).To(Equal("none"))
+			Expect(registry.Apply("x", FormatTransformer{Name: DefaultIfEmpty, Args: []string{"none"}})).To(Equal("x"))
+		})
+
+		It("should check the arguments", func() {
+			_, err := registry.New(FormatTransformer{Name: Split})
+			Expect(err).To(HaveOccurred())
+			_, err = registry.New(FormatTransformer{Name: Index, Args: []string{"first"}})
+			Expect(err).To(HaveOccurred())
+			_, err = registry.New(FormatTransformer{Name: ToString, Args: []string{"x"}})
+			Expect(err).To(HaveOccurred())
+			_, err = registry.Apply([]interface{}{"a"}, FormatTransformer{Name: Index, Args: []string{"3"}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should marshal names and objects", func() {
+			var fts []FormatTransformer
+			Expect(json.Unmarshal([]byte(`["ToString", {"name": "Index", "args": [2]}]`), &fts)).To(Succeed())
+			Expect(fts).To(Equal([]FormatTransformer{{Name: ToString}, {Name: Index, Args: []string{"2"}}}))
+			data, err := json.Marshal(fts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`["ToString",{"name":"Index","args":["2"]}]`))
+
+			By("keeping the JSON representation of numbers")
+			Expect(json.Unmarshal([]byte(`[{"name": "Prefix", "args": [12345678901234567890]}, {"name": "Index", "args": [1e21]}, {"name": "Suffix", "args": [true]}]`), &fts)).To(Succeed())
+			Expect(fts).To(Equal([]FormatTransformer{{Name: Prefix, Args: []string{"12345678901234567890"}}, {Name: Index, Args: []string{"1e21"}}, {Name: Suffix, Args: []string{"true"}}}))
+			Expect(json.Unmarshal([]byte(`[{"name": "Prefix", "args": [["a"]]}]`), &fts)).NotTo(Succeed())
+		})
+
+		It("should be resolved and validated in object references", func() {
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+				newConfigMap("input", nil, map[string]string{"hosts": "a.com;b.com"}),
+			).Build()
+			resolver := KubernetesResourceResolver{Client: cl, ResourcesClient: newFakeResources()}
+			ref := map[string]interface{}{"kind": "ConfigMap", "name": "input", "path": "{.data.hosts}", Transformers: []interface{}{
+				map[string]interface{}{"name": Split, "args": []interface{}{";"}},
+				map[string]interface{}{"name": Index, "args": []interface{}{int64(1)}},
+			}}
+
+			resolved := map[string]interface{}{}
+			Expect(resolver.ResolveObject(context.Background(), newTemplate(map[string]interface{}{"host": getValueFrom(ref)}), &resolved)).To(Succeed())
+			Expect(resolved["data"]).To(Equal(map[string]interface{}{"host": "b.com"}))
+			Expect(ValidateReference(ref, field.NewPath(GetValueFrom))).To(Succeed())
+
+			By("rejecting wrong arguments")
+			ref[Transformers] = []interface{}{ToString, map[string]interface{}{"name": Split}}
+			err := ValidateReference(ref, field.NewPath(GetValueFrom))
+			Expect(IsIllFormedRef(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("getValueFrom.format-transformers[1]"))
+
+			By("rejecting incompatible transformers")
+			ref[Transformers] = []interface{}{StringToInt, StringToInt, ToString, map[string]interface{}{"name": Join, "args": []interface{}{","}}}
+			err = ValidateReference(ref, field.NewPath(GetValueFrom))
+			Expect(IsIllFormedRef(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("getValueFrom.format-transformers[1]"))
+			Expect(err.Error()).To(ContainSubstring("getValueFrom.format-transformers[3]"))
+			Expect(err.Error()).NotTo(ContainSubstring("getValueFrom.format-transformers[2]"))
+			ref[Transformers] = []interface{}{StringToInt, ToString, StringToInt, map[string]interface{}{"name": DefaultIfEmpty, "args": []interface{}{"1"}}}
+			Expect(ValidateReference(ref, field.NewPath(GetValueFrom))).To(Succeed())
+
+			By("accepting user transformers after any transformer")
+			registry := NewDefaultTransformerRegistry()
+			Expect(registry.Register("ToLower", func(value interface{}) (interface{}, error) {
+				return strings.ToLower(fmt.Sprintf("%v", value)), nil
+			})).To(Succeed())
+			ref[Transformers] = []interface{}{StringToInt, "ToLower", StringToBool}
+			Expect(KubernetesResourceResolver{Transformers: registry}.ValidateReference(ref, field.NewPath(GetValueFrom))).To(Succeed())
+		})
+	})
 })
@@ -19,20 +19,36 @@ limitations under the License.
 
 package sdk
 
-import ()
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComposableGetValueFrom) DeepCopyInto(out *ComposableGetValueFrom) {
 	*out = *in
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WaitFor != nil {
+		in, out := &in.WaitFor, &out.WaitFor
+		*out = new(ComposableWaitFor)
+		**out = **in
 	}
 	if in.FormatTransformers != nil {
 		in, out := &in.FormatTransformers, &out.FormatTransformers
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+		*out = make([]FormatTransformer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -46,6 +62,41 @@ func (in *ComposableGetValueFrom) DeepCopy() *ComposableGetValueFrom {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComposableWaitFor) DeepCopyInto(out *ComposableWaitFor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComposableWaitFor.
+func (in *ComposableWaitFor) DeepCopy() *ComposableWaitFor {
+	if in == nil {
+		return nil
+	}
+	out := new(ComposableWaitFor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FormatTransformer) DeepCopyInto(out *FormatTransformer) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FormatTransformer.
+func (in *FormatTransformer) DeepCopy() *FormatTransformer {
+	if in == nil {
+		return nil
+	}
+	out := new(FormatTransformer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectRef) DeepCopyInto(out *ObjectRef) {
 	*out = *in